@@ -1,6 +1,7 @@
 package api2go
 
 import (
+	"crypto/sha1"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -8,6 +9,7 @@ import (
 	"net/http"
 	"net/url"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -50,7 +52,7 @@ func (i information) GetPrefix() string {
 }
 
 type paginationQueryParams struct {
-	number, size, offset, limit string
+	number, size, offset, limit, after, before string
 }
 
 func newPaginationQueryParams(r *http.Request) paginationQueryParams {
@@ -61,10 +63,151 @@ func newPaginationQueryParams(r *http.Request) paginationQueryParams {
 	result.size = queryParams.Get("page[size]")
 	result.offset = queryParams.Get("page[offset]")
 	result.limit = queryParams.Get("page[limit]")
+	result.after = queryParams.Get("page[after]")
+	result.before = queryParams.Get("page[before]")
 
 	return result
 }
 
+// paginationOptions returns the pagination options of a resource, which default to the options of the api
+func (api *API) paginationOptions(res *resource) PaginationOptions {
+	if res.pagination != nil {
+		return *res.pagination
+	}
+
+	return api.pagination
+}
+
+// applyPagination adds default page sizes to the query of the request and checks the maximum page
+// sizes, so that the sources as well as the generated links see the effective page parameters
+func (res *resource) applyPagination(r *http.Request, options PaginationOptions) error {
+	defaultSize := options.DefaultSize
+	if defaultSize == 0 {
+		defaultSize = options.MaxSize
+	}
+
+	p := newPaginationQueryParams(r)
+	params := r.URL.Query()
+	changed := false
+	set := func(parameter, value string) {
+		params.Set(parameter, value)
+		changed = true
+	}
+
+	if defaultSize > 0 {
+		size := strconv.FormatUint(uint64(defaultSize), 10)
+		switch {
+		case p.number != "" && p.size == "":
+			set("page[size]", size)
+		case p.offset != "" && p.limit == "":
+			set("page[limit]", size)
+		case (p.after != "" || p.before != "") && p.size == "":
+			set("page[size]", size)
+		case p == paginationQueryParams{} && options.Force:
+			// fall back to the first page of PaginatedFindAll or CursorPaginatedFindAll instead of FindAll
+			if res.isPaginated() {
+				set("page[number]", "1")
+				set("page[size]", size)
+			} else if res.isCursorPaginated() {
+				set("page[size]", size)
+			}
+		}
+	}
+
+	if options.MaxSize > 0 {
+		for _, parameter := range []string{"page[size]", "page[limit]"} {
+			value := params.Get(parameter)
+			if value == "" {
+				continue
+			}
+
+			size, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return newParameterError(parameter, "Invalid page size", parameter+" must be a positive integer")
+			}
+
+			if size > uint64(options.MaxSize) {
+				if !options.Clamp {
+					return newParameterError(parameter, "Invalid page size", fmt.Sprintf("%s must not be greater than %d", parameter, options.MaxSize))
+				}
+
+				set(parameter, strconv.FormatUint(uint64(options.MaxSize), 10))
+			}
+		}
+	}
+
+	if changed {
+		r.URL.RawQuery = params.Encode()
+	}
+
+	return nil
+}
+
+// paginationMeta returns the total count and, for page[number] and page[size], the number of pages
+// for the meta of a paginated document, unless the resource opted out
+func (res *resource) paginationMeta(p Pagination, count uint) map[string]interface{} {
+	if res.noPaginationMeta {
+		return nil
+	}
+
+	meta := map[string]interface{}{"total": count}
+	if p.Size > 0 && p.Number > 0 {
+		meta["pages"] = (count + p.Size - 1) / p.Size
+	}
+
+	return meta
+}
+
+// isCursor returns if the parameters request a page of cursor pagination, which is either
+// identified by a cursor or only by its size for the first page
+func (p paginationQueryParams) isCursor() bool {
+	if p.number != "" || p.offset != "" || p.limit != "" {
+		return false
+	}
+
+	return p.after != "" || p.before != "" || p.size != ""
+}
+
+// getCursorLinks returns the prev and next links of cursor pagination
+func (p paginationQueryParams) getCursorLinks(r *http.Request, cursors Cursors, info information) map[string]string {
+	result := make(map[string]string)
+	params := r.URL.Query()
+	requestURL := fmt.Sprintf("%s%s", info.GetBaseURL(), r.URL.Path)
+
+	if cursors.Prev != "" {
+		params.Del("page[after]")
+		params.Set("page[before]", cursors.Prev)
+		result["prev"] = fmt.Sprintf("%s?%s", requestURL, encodeQueryValues(params))
+	}
+
+	if cursors.Next != "" {
+		params.Del("page[before]")
+		params.Set("page[after]", cursors.Next)
+		result["next"] = fmt.Sprintf("%s?%s", requestURL, encodeQueryValues(params))
+	}
+
+	return result
+}
+
+// encodeQueryValues encodes params sorted by key like url.Values.Encode, but only escapes the values,
+// so that the keys stay readable while opaque values like base64 cursors survive the round trip
+func encodeQueryValues(params url.Values) string {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		for _, value := range params[key] {
+			parts = append(parts, key+"="+url.QueryEscape(value))
+		}
+	}
+
+	return strings.Join(parts, "&")
+}
+
 func (p paginationQueryParams) isValid() bool {
 	if p.number == "" && p.size == "" && p.offset == "" && p.limit == "" {
 		return false
@@ -81,117 +224,228 @@ func (p paginationQueryParams) isValid() bool {
 	return false
 }
 
-func (p paginationQueryParams) getLinks(r *http.Request, count uint, info information) (result map[string]string, err error) {
-	result = make(map[string]string)
+// parse strictly parses all given page parameters, page[number], page[size] and page[limit] must be
+// positive and page[offset] non-negative integers
+func (p paginationQueryParams) parse() (result Pagination, err error) {
+	parseUint := func(parameter, value string, min uint64) uint {
+		if err != nil || value == "" {
+			return 0
+		}
+
+		parsed, parseErr := strconv.ParseUint(value, 10, 0)
+		if parseErr != nil || parsed < min {
+			qualifier := "positive"
+			if min == 0 {
+				qualifier = "non-negative"
+			}
+			err = newParameterError(parameter, "Invalid page parameter", fmt.Sprintf("%s must be a %s integer", parameter, qualifier))
+		}
+
+		return uint(parsed)
+	}
+
+	result.Number = parseUint("page[number]", p.number, 1)
+	result.Size = parseUint("page[size]", p.size, 1)
+	result.Offset = parseUint("page[offset]", p.offset, 0)
+	result.Limit = parseUint("page[limit]", p.limit, 1)
+	result.After = p.after
+	result.Before = p.before
+
+	return result, err
+}
 
+// getLinks returns the first, prev, next and last links of page[number] and page[size] or
+// page[offset] and page[limit] pagination. Links of pages past the last page refer to the last page.
+func (p Pagination) getLinks(r *http.Request, count uint, info information) map[string]string {
+	result := make(map[string]string)
 	params := r.URL.Query()
-	prefix := ""
-	baseURL := info.GetBaseURL()
-	if baseURL != "" {
-		prefix = baseURL
+	requestURL := fmt.Sprintf("%s%s", info.GetBaseURL(), r.URL.Path)
+
+	link := func(name, parameter string, value uint) {
+		params.Set(parameter, strconv.FormatUint(uint64(value), 10))
+		query, _ := url.QueryUnescape(params.Encode())
+		result[name] = fmt.Sprintf("%s?%s", requestURL, query)
 	}
-	requestURL := fmt.Sprintf("%s%s", prefix, r.URL.Path)
 
-	if p.number != "" {
-		// we have number & size params
-		var number uint64
-		number, err = strconv.ParseUint(p.number, 10, 64)
-		if err != nil {
-			return
+	if p.Size > 0 {
+		// without any resources, the first page is the last one
+		lastPage := (count + p.Size - 1) / p.Size
+		if lastPage == 0 {
+			lastPage = 1
 		}
 
-		if p.number != "1" {
-			params.Set("page[number]", "1")
-			query, _ := url.QueryUnescape(params.Encode())
-			result["first"] = fmt.Sprintf("%s?%s", requestURL, query)
+		if p.Number > 1 {
+			link("first", "page[number]", 1)
 
-			params.Set("page[number]", strconv.FormatUint(number-1, 10))
-			query, _ = url.QueryUnescape(params.Encode())
-			result["prev"] = fmt.Sprintf("%s?%s", requestURL, query)
+			prevPage := p.Number - 1
+			if prevPage > lastPage {
+				prevPage = lastPage
+			}
+			link("prev", "page[number]", prevPage)
 		}
 
-		// calculate last page number
-		var size uint64
-		size, err = strconv.ParseUint(p.size, 10, 64)
-		if err != nil {
-			return
+		if p.Number < lastPage {
+			link("next", "page[number]", p.Number+1)
 		}
-		totalPages := (uint64(count) / size)
-		if (uint64(count) % size) != 0 {
-			// there is one more page with some len(items) < size
-			totalPages++
+
+		if p.Number != lastPage {
+			link("last", "page[number]", lastPage)
 		}
 
-		if number != totalPages {
-			params.Set("page[number]", strconv.FormatUint(number+1, 10))
-			query, _ := url.QueryUnescape(params.Encode())
-			result["next"] = fmt.Sprintf("%s?%s", requestURL, query)
+		return result
+	}
 
-			params.Set("page[number]", strconv.FormatUint(totalPages, 10))
-			query, _ = url.QueryUnescape(params.Encode())
-			result["last"] = fmt.Sprintf("%s?%s", requestURL, query)
-		}
-	} else {
-		// we have offset & limit params
-		var offset, limit uint64
-		offset, err = strconv.ParseUint(p.offset, 10, 64)
-		if err != nil {
-			return
-		}
-		limit, err = strconv.ParseUint(p.limit, 10, 64)
-		if err != nil {
-			return
-		}
+	var lastOffset uint
+	if count > p.Limit {
+		lastOffset = count - p.Limit
+	}
 
-		if p.offset != "0" {
-			params.Set("page[offset]", "0")
-			query, _ := url.QueryUnescape(params.Encode())
-			result["first"] = fmt.Sprintf("%s?%s", requestURL, query)
+	if p.Offset > 0 {
+		link("first", "page[offset]", 0)
 
-			var prevOffset uint64
-			if limit > offset {
-				prevOffset = 0
-			} else {
-				prevOffset = offset - limit
-			}
-			params.Set("page[offset]", strconv.FormatUint(prevOffset, 10))
-			query, _ = url.QueryUnescape(params.Encode())
-			result["prev"] = fmt.Sprintf("%s?%s", requestURL, query)
+		var prevOffset uint
+		if p.Offset > p.Limit {
+			prevOffset = p.Offset - p.Limit
 		}
+		if prevOffset > lastOffset {
+			prevOffset = lastOffset
+		}
+		link("prev", "page[offset]", prevOffset)
+	}
 
-		// check if there are more entries to be loaded
-		if (offset + limit) < uint64(count) {
-			params.Set("page[offset]", strconv.FormatUint(offset+limit, 10))
-			query, _ := url.QueryUnescape(params.Encode())
-			result["next"] = fmt.Sprintf("%s?%s", requestURL, query)
+	if p.Offset+p.Limit < count {
+		link("next", "page[offset]", p.Offset+p.Limit)
+	}
 
-			params.Set("page[offset]", strconv.FormatUint(uint64(count)-limit, 10))
-			query, _ = url.QueryUnescape(params.Encode())
-			result["last"] = fmt.Sprintf("%s?%s", requestURL, query)
-		}
+	if p.Offset != lastOffset {
+		link("last", "page[offset]", lastOffset)
 	}
 
-	return
+	return result
+}
+
+// newMethodNotAllowedError returns the error of operations that the source of a resource does not implement
+func newMethodNotAllowedError(detail string) HTTPError {
+	httpErr := NewHTTPError(nil, "Method Not Allowed", http.StatusMethodNotAllowed)
+	httpErr.Errors = []Error{{
+		Status: strconv.Itoa(http.StatusMethodNotAllowed),
+		Title:  "Method Not Allowed",
+		Detail: detail,
+	}}
+
+	return httpErr
 }
 
 type notAllowedHandler struct {
-	marshalers map[string]ContentMarshaler
+	api *API
 }
 
 func (n notAllowedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Allow", n.api.allowedMethods(r.URL.Path))
 	err := NewHTTPError(nil, "Method Not Allowed", http.StatusMethodNotAllowed)
-	w.WriteHeader(http.StatusMethodNotAllowed)
-	handleError(err, w, r, n.marshalers)
+	n.api.handleError(err, w, r, Route{}, n.api.marshalers)
+}
+
+// notFoundHandler answers requests to paths with only an OPTIONS route, e.g. the resource path of
+// sources that only implement Creator, with 405 Method Not Allowed instead of 404 Not Found
+type notFoundHandler struct {
+	api *API
+}
+
+func (n notFoundHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if handle, _, _ := n.api.router.Lookup("OPTIONS", r.URL.Path); handle != nil {
+		notAllowedHandler{api: n.api}.ServeHTTP(w, r)
+		return
+	}
+
+	http.NotFound(w, r)
+}
+
+// routeMethods are all methods that can be registered with route, in the order of the Allow header
+var routeMethods = []string{"GET", "POST", "PATCH", "DELETE"}
+
+// route registers handle for method and path. The first route of a path also registers its OPTIONS
+// handler with options.
+func (api *API) route(method, path string, handle httprouter.Handle) {
+	api.router.Handle(method, path, handle)
+	api.options(path)
+}
+
+// options registers the OPTIONS handler of path once, which answers with the methods of all routes
+// of the path and CORS preflight requests.
+func (api *API) options(path string) {
+	if api.optionsPaths[path] {
+		return
+	}
+
+	api.optionsPaths[path] = true
+	api.router.OPTIONS(path, func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		allowed := api.allowedMethods(r.URL.Path)
+		w.Header().Set("Allow", allowed)
+		api.handlePreflight(w, r, allowed)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// allowedMethods returns the value of the Allow header for path, e.g. "GET,PATCH,DELETE,OPTIONS"
+func (api *API) allowedMethods(path string) string {
+	allowed := []string{}
+	for _, method := range routeMethods {
+		if handle, _, _ := api.router.Lookup(method, path); handle != nil {
+			allowed = append(allowed, method)
+		}
+	}
+
+	return strings.Join(append(allowed, "OPTIONS"), ",")
 }
 
 type resource struct {
 	resourceType reflect.Type
-	source       CRUD
+	source       interface{}
 	name         string
 	marshalers   map[string]ContentMarshaler
+	middlewares  []Middleware
+	etags        bool
+	pagination   *PaginationOptions
+	// noPaginationMeta omits meta.total and meta.pages of paginated documents
+	noPaginationMeta bool
+	clientIDs        ClientIDPolicy
+	// validations contains the validation settings of the struct tags of the attributes
+	validations []attributeValidation
 }
 
-func (api *API) addResource(prototype jsonapi.MarshalIdentifier, source CRUD, marshalers map[string]ContentMarshaler) *resource {
+// handle returns a router handle that runs the middlewares of the api and the resource before
+// the resource handler and writes the error of the resource handler, if any
+func (api *API) handle(res *resource, route Route, handler func(http.ResponseWriter, *http.Request, httprouter.Params) error) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		api.setCORSHeaders(w, r)
+		r = api.mediaTypes.withMediaTypes(r)
+
+		chain := func(w http.ResponseWriter, r *http.Request) {
+			err := api.checkMediaTypes(r)
+			if err == nil {
+				err = handler(w, r, ps)
+			}
+			if err != nil {
+				api.handleError(err, w, r, route, res.marshalers)
+			}
+		}
+
+		middlewares := make([]Middleware, 0, len(api.middlewares)+len(res.middlewares))
+		middlewares = append(middlewares, api.middlewares...)
+		middlewares = append(middlewares, res.middlewares...)
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			middleware, next := middlewares[i], chain
+			chain = func(w http.ResponseWriter, r *http.Request) {
+				middleware(w, r, route, next)
+			}
+		}
+
+		chain(w, r)
+	}
+}
+
+func (api *API) addResource(prototype jsonapi.MarshalIdentifier, source interface{}, marshalers map[string]ContentMarshaler, options ...ResourceOption) *resource {
 	resourceType := reflect.TypeOf(prototype)
 	if resourceType.Kind() != reflect.Struct && resourceType.Kind() != reflect.Ptr {
 		panic("pass an empty resource struct or a struct pointer to AddResource!")
@@ -221,173 +475,681 @@ func (api *API) addResource(prototype jsonapi.MarshalIdentifier, source CRUD, ma
 		name:         name,
 		source:       source,
 		marshalers:   marshalers,
+		validations:  parseValidations(prototype),
 	}
 
-	api.router.Handle("OPTIONS", api.prefix+name, func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-		w.Header().Set("Allow", "GET,POST,PATCH,OPTIONS")
-		w.WriteHeader(http.StatusNoContent)
-	})
+	for _, option := range options {
+		option(&res)
+	}
 
-	api.router.Handle("OPTIONS", api.prefix+name+"/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-		w.Header().Set("Allow", "GET,PATCH,DELETE,OPTIONS")
-		w.WriteHeader(http.StatusNoContent)
-	})
+	if !res.canFind() && !res.canCreate() && !res.canUpdate() && !res.canDelete() && !res.isBulkCreator() &&
+		!res.isFindAll() && !res.isPaginated() && !res.isCursorPaginated() {
+		panic(fmt.Sprintf("source of %s must implement at least one of Finder, Creator, Updater, Deleter or FindAll", name))
+	}
 
-	api.router.GET(api.prefix+name, func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-		err := res.handleIndex(w, r, api.info)
-		if err != nil {
-			handleError(err, w, r, marshalers)
-		}
-	})
+	if res.isFindAll() || res.isPaginated() || res.isCursorPaginated() {
+		api.route("GET", api.prefix+name, api.handle(&res, Route{Resource: name, Operation: OperationIndex},
+			func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) error {
+				if err := api.checkIncludeParameter(name, r); err != nil {
+					return err
+				}
 
-	api.router.GET(api.prefix+name+"/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-		err := res.handleRead(w, r, ps, api.info)
-		if err != nil {
-			handleError(err, w, r, marshalers)
-		}
-	})
+				if err := res.applyPagination(r, api.paginationOptions(&res)); err != nil {
+					return err
+				}
+
+				return res.handleIndex(w, r, api.info)
+			}))
+	}
+
+	if res.canCreate() || res.isBulkCreator() {
+		api.route("POST", api.prefix+name, api.handle(&res, Route{Resource: name, Operation: OperationCreate},
+			func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) error {
+				if err := api.checkIncludeParameter(name, r); err != nil {
+					return err
+				}
+
+				return res.handleCreate(w, r, api.prefix, api.info)
+			}))
+	}
+
+	if res.canFind() {
+		api.route("GET", api.prefix+name+"/:id", api.handle(&res, Route{Resource: name, Operation: OperationRead},
+			func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) error {
+				if err := api.checkIncludeParameter(name, r); err != nil {
+					return err
+				}
+
+				return res.handleRead(w, r, ps, api.info)
+			}))
+	}
+
+	if res.canUpdate() {
+		api.route("PATCH", api.prefix+name+"/:id", api.handle(&res, Route{Resource: name, Operation: OperationUpdate},
+			func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) error {
+				if err := api.checkIncludeParameter(name, r); err != nil {
+					return err
+				}
+
+				return res.handleUpdate(w, r, ps, api.info)
+			}))
+	}
+
+	if res.canDelete() {
+		api.route("DELETE", api.prefix+name+"/:id", api.handle(&res, Route{Resource: name, Operation: OperationDelete},
+			func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) error {
+				return res.handleDelete(w, r, ps, api.info)
+			}))
+	}
+
+	// the resource path still answers with 405 Method Not Allowed if no operation is implemented for it
+	if !res.canFind() && !res.canUpdate() && !res.canDelete() {
+		api.options(api.prefix + name + "/:id")
+	}
 
 	// generate all routes for linked relations if there are relations
 	casted, ok := prototype.(jsonapi.MarshalReferences)
 	if ok {
 		relations := casted.GetReferences()
 		for _, relation := range relations {
-			api.router.GET(api.prefix+name+"/:id/relationships/"+relation.Name, func(relation jsonapi.Reference) httprouter.Handle {
-				return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-					err := res.handleReadRelation(w, r, ps, api.info, relation)
-					if err != nil {
-						handleError(err, w, r, marshalers)
+			// copy the loop variable for the handler closures
+			relation := relation
+			route := Route{Resource: name, Operation: OperationRelationship, Relation: relation.Name}
+
+			api.route("GET", api.prefix+name+"/:id/"+relation.Name, api.handle(&res, route,
+				func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) error {
+					if err := api.checkIncludeParameter(relation.Type, r); err != nil {
+						return err
 					}
-				}
-			}(relation))
 
-			api.router.GET(api.prefix+name+"/:id/"+relation.Name, func(relation jsonapi.Reference) httprouter.Handle {
-				return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-					err := res.handleLinked(api, w, r, ps, relation, api.info)
-					if err != nil {
-						handleError(err, w, r, marshalers)
-					}
+					return res.handleLinked(api, w, r, ps, relation, api.info)
+				}))
+
+			if res.canFind() {
+				api.route("GET", api.prefix+name+"/:id/relationships/"+relation.Name, api.handle(&res, route,
+					func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) error {
+						return res.handleReadRelation(w, r, ps, api.info, relation)
+					}))
+			}
+
+			// relationships are changed by updating the object that was found
+			if !res.canUpdate() {
+				continue
+			}
+
+			api.route("PATCH", api.prefix+name+"/:id/relationships/"+relation.Name, api.handle(&res, route,
+				func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) error {
+					return res.handleReplaceRelation(w, r, ps, relation)
+				}))
+
+			if _, ok := ptrPrototype.(jsonapi.EditToManyRelations); ok && isToMany(relation) {
+				// generate additional routes to manipulate to-many relationships
+				api.route("POST", api.prefix+name+"/:id/relationships/"+relation.Name, api.handle(&res, route,
+					func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) error {
+						return res.handleAddToManyRelation(w, r, ps, relation)
+					}))
+
+				api.route("DELETE", api.prefix+name+"/:id/relationships/"+relation.Name, api.handle(&res, route,
+					func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) error {
+						return res.handleDeleteToManyRelation(w, r, ps, relation)
+					}))
+			}
+		}
+	}
+
+	api.resources = append(api.resources, res)
+
+	return &res
+}
+
+// prototype returns a pointer to a new struct of the resource type, which also provides all
+// methods with value receivers
+func (res *resource) prototype() jsonapi.MarshalIdentifier {
+	structType := res.resourceType
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+
+	return reflect.New(structType).Interface().(jsonapi.MarshalIdentifier)
+}
+
+// references returns the relationships of the resource prototype
+func (res *resource) references() []jsonapi.Reference {
+	prototype, ok := res.prototype().(jsonapi.MarshalReferences)
+	if !ok {
+		return []jsonapi.Reference{}
+	}
+
+	return prototype.GetReferences()
+}
+
+// isToMany returns if a relation is to-many, which are the relations with a plural name
+func isToMany(relation jsonapi.Reference) bool {
+	return relation.Name == jsonapi.Pluralize(relation.Name)
+}
+
+// parseIncludeParameter returns all relationship paths of the include query parameter
+// or nil if the parameter was not set.
+func parseIncludeParameter(r *http.Request) []string {
+	values, ok := r.URL.Query()["include"]
+	if !ok {
+		return nil
+	}
+
+	include := []string{}
+	for _, value := range values {
+		for _, path := range strings.Split(value, ",") {
+			path = strings.TrimSpace(path)
+			if path != "" {
+				include = append(include, path)
+			}
+		}
+	}
+
+	return include
+}
+
+// checkIncludeParameter validates every relationship path of the include query parameter
+// against the references of the resource with the given name and its related resources.
+func (api *API) checkIncludeParameter(name string, r *http.Request) error {
+	for _, path := range parseIncludeParameter(r) {
+		resourceName := name
+		for _, relationName := range strings.Split(path, ".") {
+			var res *resource
+			for i := range api.resources {
+				if api.resources[i].name == resourceName {
+					res = &api.resources[i]
+					break
 				}
-			}(relation))
+			}
 
-			api.router.PATCH(api.prefix+name+"/:id/relationships/"+relation.Name, func(relation jsonapi.Reference) httprouter.Handle {
-				return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-					err := res.handleReplaceRelation(w, r, ps, relation)
-					if err != nil {
-						handleError(err, w, r, marshalers)
+			found := false
+			if res != nil {
+				for _, reference := range res.references() {
+					if reference.Name == relationName {
+						resourceName = reference.Type
+						found = true
+						break
 					}
 				}
-			}(relation))
+			}
 
-			if _, ok := ptrPrototype.(jsonapi.EditToManyRelations); ok && relation.Name == jsonapi.Pluralize(relation.Name) {
-				// generate additional routes to manipulate to-many relationships
-				api.router.POST(api.prefix+name+"/:id/relationships/"+relation.Name, func(relation jsonapi.Reference) httprouter.Handle {
-					return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-						err := res.handleAddToManyRelation(w, r, ps, relation)
-						if err != nil {
-							handleError(err, w, r, marshalers)
-						}
-					}
-				}(relation))
-
-				api.router.DELETE(api.prefix+name+"/:id/relationships/"+relation.Name, func(relation jsonapi.Reference) httprouter.Handle {
-					return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-						err := res.handleDeleteToManyRelation(w, r, ps, relation)
-						if err != nil {
-							handleError(err, w, r, marshalers)
-						}
-					}
-				}(relation))
+			if !found {
+				return newParameterError(
+					"include",
+					"Invalid include parameter",
+					fmt.Sprintf("%s has no relationship %s which could be included", resourceName, relationName),
+				)
 			}
 		}
 	}
 
-	api.router.POST(api.prefix+name, func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-		err := res.handleCreate(w, r, api.prefix, api.info)
-		if err != nil {
-			handleError(err, w, r, marshalers)
+	return nil
+}
+
+// parseFieldsParameters returns the sparse fieldsets of all fields[type] query parameters
+// or nil if there are none.
+func parseFieldsParameters(r *http.Request) map[string][]string {
+	var fields map[string][]string
+	for key, values := range r.URL.Query() {
+		if !strings.HasPrefix(key, "fields[") || !strings.HasSuffix(key, "]") {
+			continue
 		}
-	})
 
-	api.router.DELETE(api.prefix+name+"/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-		err := res.handleDelete(w, r, ps)
-		if err != nil {
-			handleError(err, w, r, marshalers)
+		if fields == nil {
+			fields = map[string][]string{}
 		}
-	})
 
-	api.router.PATCH(api.prefix+name+"/:id", func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-		err := res.handleUpdate(w, r, ps)
-		if err != nil {
-			handleError(err, w, r, marshalers)
+		typeName := strings.TrimSuffix(strings.TrimPrefix(key, "fields["), "]")
+		fields[typeName] = []string{}
+		for _, value := range values {
+			for _, field := range strings.Split(value, ",") {
+				field = strings.TrimSpace(field)
+				if field != "" {
+					fields[typeName] = append(fields[typeName], field)
+				}
+			}
 		}
+	}
+
+	return fields
+}
+
+// marshalOptions returns the jsonapi marshal options requested by the query parameters
+func marshalOptions(r *http.Request) jsonapi.MarshalOptions {
+	return jsonapi.MarshalOptions{
+		Include: parseIncludeParameter(r),
+		Fields:  parseFieldsParameters(r),
+	}
+}
+
+// newParameterError returns a 400 error for an invalid query parameter
+func newParameterError(parameter, title, detail string) HTTPError {
+	err := NewHTTPError(nil, title, http.StatusBadRequest)
+	err.Errors = append(err.Errors, Error{
+		Status: strconv.Itoa(http.StatusBadRequest),
+		Title:  title,
+		Detail: detail,
+		Source: &ErrorSource{Parameter: parameter},
 	})
 
-	api.resources = append(api.resources, res)
+	return err
+}
 
-	return &res
+// newPointerError returns an error for the member of the request document the pointer refers to
+func newPointerError(status int, title, detail, pointer string) HTTPError {
+	httpErr := NewHTTPError(errors.New(title), title, status)
+	httpErr.Errors = []Error{{
+		Status: strconv.Itoa(status),
+		Title:  title,
+		Detail: detail,
+		Source: &ErrorSource{Pointer: pointer},
+	}}
+
+	return httpErr
+}
+
+// parseSortParameter returns the sort fields of the sort query parameter in the requested order
+func parseSortParameter(r *http.Request) []SortField {
+	var result []SortField
+	for _, value := range r.URL.Query()["sort"] {
+		for _, field := range strings.Split(value, ",") {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+
+			if strings.HasPrefix(field, "-") {
+				result = append(result, SortField{Field: field[1:], Descending: true})
+			} else {
+				result = append(result, SortField{Field: field})
+			}
+		}
+	}
+
+	return result
+}
+
+// checkSortParameter rejects all sort fields that are not allowed by the SortableFields interface.
+// Resources that do not implement it have to check the sort fields on their own.
+func (res *resource) checkSortParameter(req Request) error {
+	source, ok := res.source.(SortableFields)
+	if !ok {
+		return nil
+	}
+
+	allowed := source.SortableFields()
+	for _, sortField := range req.Sort {
+		found := false
+		for _, field := range allowed {
+			if field == sortField.Field {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return newParameterError(
+				"sort",
+				"Invalid sort parameter",
+				fmt.Sprintf("%s can not be sorted by %s", res.name, sortField.Field),
+			)
+		}
+	}
+
+	return nil
+}
+
+// parseFilterParameters returns all filter[...] query parameters sorted by their name.
+// Unknown operators are kept, checkFilterParameters rejects them for resources with FilterableFields.
+func parseFilterParameters(r *http.Request) []Filter {
+	var (
+		result []Filter
+		keys   []string
+	)
+
+	query := r.URL.Query()
+	for key := range query {
+		if strings.HasPrefix(key, "filter[") && strings.HasSuffix(key, "]") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		parts := strings.Split(strings.TrimSuffix(strings.TrimPrefix(key, "filter["), "]"), "][")
+		filter := Filter{Field: parts[0], Operator: FilterEqual, Values: []string{}, Parameter: key}
+		if len(parts) > 1 {
+			filter.Operator = FilterOperator(strings.Join(parts[1:], "]["))
+		}
+
+		for _, value := range query[key] {
+			filter.Values = append(filter.Values, strings.Split(value, ",")...)
+		}
+
+		result = append(result, filter)
+	}
+
+	return result
+}
+
+// checkFilterParameters rejects filters with unknown operators and all filters that are not allowed by
+// the FilterableFields interface if the resource implements it. Other resources may parse filter
+// parameters of their own from QueryParams.
+func (res *resource) checkFilterParameters(req Request) error {
+	source, isFilterable := res.source.(FilterableFields)
+	if !isFilterable {
+		return nil
+	}
+
+	allowed := source.FilterableFields()
+	for _, filter := range req.Filter {
+		switch filter.Operator {
+		case FilterEqual, FilterNotEqual, FilterLessThan, FilterGreaterThan, FilterIn, FilterLike:
+		default:
+			return newParameterError(
+				filter.Parameter,
+				"Invalid filter parameter",
+				fmt.Sprintf("unknown filter operator %s", filter.Operator),
+			)
+		}
+
+		operators, ok := allowed[filter.Field]
+		if !ok {
+			return newParameterError(
+				filter.Parameter,
+				"Invalid filter parameter",
+				fmt.Sprintf("%s can not be filtered by %s", res.name, filter.Field),
+			)
+		}
+
+		found := false
+		for _, operator := range operators {
+			if operator == filter.Operator {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return newParameterError(
+				filter.Parameter,
+				"Invalid filter parameter",
+				fmt.Sprintf("%s can not be filtered by %s with operator %s", res.name, filter.Field, filter.Operator),
+			)
+		}
+	}
+
+	return nil
+}
+
+// checkRequest validates the parsed query parameters of a request for FindAll or PaginatedFindAll
+func (res *resource) checkRequest(req Request) error {
+	if err := res.checkSortParameter(req); err != nil {
+		return err
+	}
+
+	return res.checkFilterParameters(req)
+}
+
+// findAll calls FindAllContext if the source implements it, FindAll otherwise
+func (res *resource) findAll(req Request) (Responder, error) {
+	if source, ok := res.source.(FindAllContext); ok {
+		return source.FindAllContext(req.Context(), req)
+	}
+
+	source, ok := res.source.(FindAll)
+	if !ok {
+		return nil, NewHTTPError(nil, "Resource does not implement the FindAll interface", http.StatusNotFound)
+	}
+
+	return source.FindAll(req)
+}
+
+// paginatedFindAll calls PaginatedFindAllContext if the source implements it, PaginatedFindAll otherwise
+func (res *resource) paginatedFindAll(req Request) (uint, Responder, error) {
+	if source, ok := res.source.(PaginatedFindAllContext); ok {
+		return source.PaginatedFindAllContext(req.Context(), req)
+	}
+
+	source, ok := res.source.(PaginatedFindAll)
+	if !ok {
+		return 0, nil, NewHTTPError(nil, "Resource does not implement the PaginatedFindAll interface", http.StatusNotFound)
+	}
+
+	return source.PaginatedFindAll(req)
+}
+
+// cursorPaginatedFindAll calls CursorPaginatedFindAllContext if the source implements it, CursorPaginatedFindAll otherwise
+func (res *resource) cursorPaginatedFindAll(req Request) (Cursors, Responder, error) {
+	if source, ok := res.source.(CursorPaginatedFindAllContext); ok {
+		return source.CursorPaginatedFindAllContext(req.Context(), req)
+	}
+
+	source, ok := res.source.(CursorPaginatedFindAll)
+	if !ok {
+		return Cursors{}, nil, NewHTTPError(nil, "Resource does not implement the CursorPaginatedFindAll interface", http.StatusNotFound)
+	}
+
+	return source.CursorPaginatedFindAll(req)
+}
+
+// isPaginated returns if the source implements one of the PaginatedFindAll interfaces
+func (res *resource) isPaginated() bool {
+	_, paginated := res.source.(PaginatedFindAll)
+	_, paginatedContext := res.source.(PaginatedFindAllContext)
+	return paginated || paginatedContext
+}
+
+// isCursorPaginated returns if the source implements one of the cursor pagination interfaces
+func (res *resource) isCursorPaginated() bool {
+	_, cursor := res.source.(CursorPaginatedFindAll)
+	_, cursorContext := res.source.(CursorPaginatedFindAllContext)
+	return cursor || cursorContext
+}
+
+// isFindAll returns if the source implements one of the FindAll interfaces
+func (res *resource) isFindAll() bool {
+	_, findAll := res.source.(FindAll)
+	_, findAllContext := res.source.(FindAllContext)
+	return findAll || findAllContext
+}
+
+// isBulkCreator returns if the source implements BulkCreator
+func (res *resource) isBulkCreator() bool {
+	_, bulk := res.source.(BulkCreator)
+	return bulk
+}
+
+// canFind returns if the source implements Finder or FindOneContext
+func (res *resource) canFind() bool {
+	_, finder := res.source.(Finder)
+	_, finderContext := res.source.(FindOneContext)
+	return finder || finderContext
+}
+
+// canCreate returns if the source implements Creator or CreateContext
+func (res *resource) canCreate() bool {
+	_, creator := res.source.(Creator)
+	_, creatorContext := res.source.(CreateContext)
+	return creator || creatorContext
+}
+
+// canUpdate returns if the source implements Updater or UpdateContext and can find the objects to update
+func (res *resource) canUpdate() bool {
+	_, updater := res.source.(Updater)
+	_, updaterContext := res.source.(UpdateContext)
+	return (updater || updaterContext) && res.canFind()
+}
+
+// canDelete returns if the source implements Deleter or DeleteContext
+func (res *resource) canDelete() bool {
+	_, deleter := res.source.(Deleter)
+	_, deleterContext := res.source.(DeleteContext)
+	return deleter || deleterContext
+}
+
+// findOne calls FindOneContext if the source implements it, FindOne otherwise
+func (res *resource) findOne(id string, req Request) (Responder, error) {
+	if source, ok := res.source.(FindOneContext); ok {
+		return source.FindOneContext(req.Context(), id, req)
+	}
+
+	source, ok := res.source.(Finder)
+	if !ok {
+		return nil, newMethodNotAllowedError(res.name + " can not be read")
+	}
+
+	return source.FindOne(id, req)
+}
+
+// create calls CreateContext if the source implements it, Create otherwise
+func (res *resource) create(obj interface{}, req Request) (Responder, error) {
+	if source, ok := res.source.(CreateContext); ok {
+		response, err := source.CreateContext(req.Context(), obj, req)
+		return response, res.duplicateIDError(obj, err)
+	}
+
+	source, ok := res.source.(Creator)
+	if !ok {
+		return nil, newMethodNotAllowedError(res.name + " can not be created")
+	}
+
+	response, err := source.Create(obj, req)
+	return response, res.duplicateIDError(obj, err)
+}
+
+// duplicateIDError points to the id of the request document if Create returned ErrDuplicateID
+func (res *resource) duplicateIDError(obj interface{}, err error) error {
+	var httpErr HTTPError
+	if !errors.Is(err, ErrDuplicateID) || errors.As(err, &httpErr) {
+		return err
+	}
+
+	id := obj.(jsonapi.MarshalIdentifier).GetID()
+	return newPointerError(http.StatusConflict, "Duplicate id", fmt.Sprintf("%s with id %s already exists", res.name, id), "/data/id")
+}
+
+// update calls UpdateContext if the source implements it, Update otherwise
+func (res *resource) update(obj interface{}, req Request) (Responder, error) {
+	if source, ok := res.source.(UpdateContext); ok {
+		return source.UpdateContext(req.Context(), obj, req)
+	}
+
+	source, ok := res.source.(Updater)
+	if !ok {
+		return nil, newMethodNotAllowedError(res.name + " can not be updated")
+	}
+
+	return source.Update(obj, req)
+}
+
+// delete calls DeleteContext if the source implements it, Delete otherwise
+func (res *resource) delete(id string, req Request) (Responder, error) {
+	if source, ok := res.source.(DeleteContext); ok {
+		return source.DeleteContext(req.Context(), id, req)
+	}
+
+	source, ok := res.source.(Deleter)
+	if !ok {
+		return nil, newMethodNotAllowedError(res.name + " can not be deleted")
+	}
+
+	return source.Delete(id, req)
 }
 
 func buildRequest(r *http.Request) Request {
-	req := Request{PlainRequest: r}
+	req := Request{PlainRequest: r, ctx: r.Context()}
 	params := make(map[string][]string)
 	for key, values := range r.URL.Query() {
 		params[key] = strings.Split(values[0], ",")
 	}
 	req.QueryParams = params
 	req.Header = r.Header
+	req.Sort = parseSortParameter(r)
+	req.Filter = parseFilterParameters(r)
+	req.Extensions, req.Profiles = mediaTypeParameters(r)
 	return req
 }
 
 func (res *resource) handleIndex(w http.ResponseWriter, r *http.Request, info information) error {
+	request := buildRequest(r)
+	if err := res.checkRequest(request); err != nil {
+		return err
+	}
+
 	pagination := newPaginationQueryParams(r)
-	if pagination.isValid() {
-		source, ok := res.source.(PaginatedFindAll)
-		if !ok {
-			return NewHTTPError(nil, "Resource does not implement the PaginatedFindAll interface", http.StatusNotFound)
+	page, err := pagination.parse()
+	if err != nil {
+		return err
+	}
+	request.Pagination = page
+
+	if res.isCursorPaginated() && (pagination.isCursor() || !res.isFindAll() && !pagination.isValid()) {
+		cursors, response, err := res.cursorPaginatedFindAll(request)
+		if err != nil {
+			return err
+		}
+
+		paginationLinks := pagination.getCursorLinks(r, cursors, info)
+
+		data, err := paginatedDocument(response, info, paginationLinks, nil, r)
+		if err != nil {
+			return err
 		}
 
-		count, response, err := source.PaginatedFindAll(buildRequest(r))
+		return res.respondToRead(response, data, w, r)
+	}
+
+	if pagination.isValid() {
+		count, response, err := res.paginatedFindAll(request)
 		if err != nil {
 			return err
 		}
 
-		paginationLinks, err := pagination.getLinks(r, count, info)
+		paginationLinks := page.getLinks(r, count, info)
+
+		data, err := paginatedDocument(response, info, paginationLinks, res.paginationMeta(page, count), r)
 		if err != nil {
 			return err
 		}
 
-		return respondWithPagination(response, info, http.StatusOK, paginationLinks, w, r, res.marshalers)
+		return res.respondToRead(response, data, w, r)
 	}
-	source, ok := res.source.(FindAll)
-	if !ok {
-		return NewHTTPError(nil, "Resource does not implement the FindAll interface", http.StatusNotFound)
+	response, err := res.findAll(request)
+	if err != nil {
+		return err
 	}
 
-	response, err := source.FindAll(buildRequest(r))
+	data, err := document(response, info, r)
 	if err != nil {
 		return err
 	}
 
-	return respondWith(response, info, http.StatusOK, w, r, res.marshalers)
+	return res.respondToRead(response, data, w, r)
 }
 
 func (res *resource) handleRead(w http.ResponseWriter, r *http.Request, ps httprouter.Params, info information) error {
 	id := ps.ByName("id")
 
-	response, err := res.source.FindOne(id, buildRequest(r))
+	response, err := res.findOne(id, buildRequest(r))
 
 	if err != nil {
 		return err
 	}
 
-	return respondWith(response, info, http.StatusOK, w, r, res.marshalers)
+	data, err := document(response, info, r)
+	if err != nil {
+		return err
+	}
+
+	return res.respondToRead(response, data, w, r)
 }
 
 func (res *resource) handleReadRelation(w http.ResponseWriter, r *http.Request, ps httprouter.Params, info information, relation jsonapi.Reference) error {
 	id := ps.ByName("id")
 
-	obj, err := res.source.FindOne(id, buildRequest(r))
+	obj, err := res.findOne(id, buildRequest(r))
 	if err != nil {
 		return err
 	}
@@ -444,38 +1206,39 @@ func (res *resource) handleLinked(api *API, w http.ResponseWriter, r *http.Reque
 	id := ps.ByName("id")
 	for _, resource := range api.resources {
 		if resource.name == linked.Type {
+			if isToMany(linked) {
+				if err := resource.applyPagination(r, api.paginationOptions(&resource)); err != nil {
+					return err
+				}
+			}
+
 			request := buildRequest(r)
 			request.QueryParams[res.name+"ID"] = []string{id}
 			request.QueryParams[res.name+"Name"] = []string{linked.Name}
+			if err := resource.checkRequest(request); err != nil {
+				return err
+			}
 
 			// check for pagination, otherwise normal FindAll
 			pagination := newPaginationQueryParams(r)
-			if pagination.isValid() {
-				source, ok := resource.source.(PaginatedFindAll)
-				if !ok {
-					return NewHTTPError(nil, "Resource does not implement the PaginatedFindAll interface", http.StatusNotFound)
-				}
-
-				var count uint
-				count, response, err := source.PaginatedFindAll(request)
-				if err != nil {
-					return err
-				}
+			page, err := pagination.parse()
+			if err != nil {
+				return err
+			}
+			request.Pagination = page
 
-				paginationLinks, err := pagination.getLinks(r, count, info)
+			if pagination.isValid() {
+				count, response, err := resource.paginatedFindAll(request)
 				if err != nil {
 					return err
 				}
 
-				return respondWithPagination(response, info, http.StatusOK, paginationLinks, w, r, res.marshalers)
-			}
+				paginationLinks := page.getLinks(r, count, info)
 
-			source, ok := resource.source.(FindAll)
-			if !ok {
-				return NewHTTPError(nil, "Resource does not implement the FindAll interface", http.StatusNotFound)
+				return respondWithPagination(response, info, http.StatusOK, paginationLinks, resource.paginationMeta(page, count), w, r, res.marshalers)
 			}
 
-			obj, err := source.FindAll(request)
+			obj, err := resource.findAll(request)
 			if err != nil {
 				return err
 			}
@@ -484,7 +1247,7 @@ func (res *resource) handleLinked(api *API, w http.ResponseWriter, r *http.Reque
 	}
 
 	err := Error{
-		Status: string(http.StatusNotFound),
+		Status: strconv.Itoa(http.StatusNotFound),
 		Title:  "Not Found",
 		Detail: "No resource handler is registered to handle the linked resource " + linked.Name,
 	}
@@ -500,35 +1263,50 @@ func (res *resource) handleCreate(w http.ResponseWriter, r *http.Request, prefix
 	if err != nil {
 		return err
 	}
-	newObjs := reflect.MakeSlice(reflect.SliceOf(res.resourceType), 0, 0)
 
-	structType := res.resourceType
-	if structType.Kind() == reflect.Ptr {
-		structType = structType.Elem()
+	if err := checkDocument(jsonapi.ValidateRequest(ctx)); err != nil {
+		return err
 	}
 
-	err = jsonapi.UnmarshalInto(ctx, structType, &newObjs)
+	if source, ok := res.source.(BulkCreator); ok {
+		if data, isArray := ctx["data"].([]interface{}); isArray {
+			return res.handleBulkCreate(w, r, source, data, info)
+		}
+	}
+
+	data, pointer, err := singleResourceObject(ctx["data"])
 	if err != nil {
 		return err
 	}
-	if newObjs.Len() != 1 {
-		return errors.New("expected one object in POST")
+
+	if err := res.checkResourceObject(data, pointer, ""); err != nil {
+		return err
 	}
 
-	//TODO create multiple objects not only one.
-	newObj := newObjs.Index(0).Interface()
+	if err := res.checkClientID(data, pointer); err != nil {
+		return err
+	}
 
-	response, err := res.source.Create(newObj, buildRequest(r))
+	newObj, err := res.unmarshalNew(ctx)
 	if err != nil {
 		return err
 	}
 
-	result, ok := response.Result().(jsonapi.MarshalIdentifier)
+	req := buildRequest(r)
+	if err := res.validate(newObj, req); err != nil {
+		return err
+	}
 
-	if !ok {
-		return fmt.Errorf("Expected one newly created object by resource %s", res.name)
+	response, err := res.create(newObj, req)
+	if err != nil {
+		return err
 	}
-	w.Header().Set("Location", prefix+res.name+"/"+result.GetID())
+
+	id, err := res.createdID(newObj, response)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Location", prefix+res.name+"/"+id)
 
 	// handle 200 status codes
 	switch response.StatusCode() {
@@ -541,75 +1319,166 @@ func (res *resource) handleCreate(w http.ResponseWriter, r *http.Request, prefix
 		w.WriteHeader(response.StatusCode())
 		return nil
 	default:
-		return fmt.Errorf("invalid status code %d from resource %s for method Create", response.StatusCode(), res.name)
+		return fmt.Errorf("invalid status code %d from resource %s for method Create", response.StatusCode(), res.name)
+	}
+}
+
+// checkClientID enforces the client id policy of the resource on the resource object of a create
+// request, pointer is the location of the resource object in the request document
+func (res *resource) checkClientID(data interface{}, pointer string) error {
+	object, ok := data.(map[string]interface{})
+	if !ok {
+		// unmarshalling reports invalid resource objects
+		return nil
+	}
+
+	hasID := object["id"] != nil && object["id"] != ""
+	switch {
+	case hasID && res.clientIDs == ClientIDsForbidden:
+		return newPointerError(http.StatusForbidden, "Client-generated id not allowed", fmt.Sprintf("%s does not accept ids generated by clients", res.name), pointer+"/id")
+	case !hasID && res.clientIDs == ClientIDsRequired:
+		return newPointerError(http.StatusUnprocessableEntity, "Missing id", fmt.Sprintf("%s must be created with an id generated by the client", res.name), pointer)
+	}
+
+	return nil
+}
+
+// createdID returns the id of the object created by Create. Sources only return the object with
+// 201 Created, with 204 No Content or 202 Accepted the id sent by the client is used. Responding with
+// 204 No Content to a create request without a client-generated id is invalid, the client would not
+// learn the id of the new resource.
+func (res *resource) createdID(newObj interface{}, response Responder) (string, error) {
+	if result, ok := response.Result().(jsonapi.MarshalIdentifier); ok {
+		return result.GetID(), nil
+	}
+
+	clientID := newObj.(jsonapi.MarshalIdentifier).GetID()
+	switch response.StatusCode() {
+	case http.StatusCreated:
+		return "", fmt.Errorf("Expected one newly created object by resource %s", res.name)
+	case http.StatusNoContent, http.StatusAccepted:
+		if clientID == "" {
+			return "", fmt.Errorf("invalid status code %d from resource %s for method Create without client-generated id", response.StatusCode(), res.name)
+		}
+	}
+
+	return clientID, nil
+}
+
+// unmarshalNew creates a new object of the resource type from a request document
+func (res *resource) unmarshalNew(ctx map[string]interface{}) (interface{}, error) {
+	newObjs := reflect.MakeSlice(reflect.SliceOf(res.resourceType), 0, 0)
+
+	structType := res.resourceType
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+
+	err := jsonapi.UnmarshalInto(ctx, structType, &newObjs)
+	if err != nil {
+		return nil, err
+	}
+	if newObjs.Len() != 1 {
+		return nil, errors.New("expected one object in POST")
+	}
+
+	return newObjs.Index(0).Interface(), nil
+}
+
+// handleBulkCreate unmarshals every element of data on its own, so that invalid elements can be reported
+// with their position, and passes all of them to BulkCreate at once
+func (res *resource) handleBulkCreate(w http.ResponseWriter, r *http.Request, source BulkCreator, data []interface{}, info information) error {
+	newObjs := []interface{}{}
+	req := buildRequest(r)
+	httpErr := NewHTTPError(nil, "Invalid objects in POST", http.StatusBadRequest)
+	validationErr := NewHTTPError(nil, "Validation failed", http.StatusUnprocessableEntity)
+	for i, element := range data {
+		if err := res.checkResourceObject(element, fmt.Sprintf("/data/%d", i), ""); err != nil {
+			return err
+		}
+
+		if err := res.checkClientID(element, fmt.Sprintf("/data/%d", i)); err != nil {
+			return err
+		}
+
+		newObj, err := res.unmarshalNew(map[string]interface{}{"data": element})
+		if err != nil {
+			httpErr.Errors = append(httpErr.Errors, Error{
+				Status: strconv.Itoa(http.StatusBadRequest),
+				Title:  "Invalid object",
+				Detail: err.Error(),
+				Source: &ErrorSource{Pointer: fmt.Sprintf("/data/%d", i)},
+			})
+			continue
+		}
+
+		if err := res.validate(newObj, req); err != nil {
+			// validation errors point into the object, so they need its position in the array
+			for _, e := range err.(HTTPError).Errors {
+				if e.Source != nil && strings.HasPrefix(e.Source.Pointer, "/data") {
+					e.Source = &ErrorSource{Pointer: fmt.Sprintf("/data/%d", i) + strings.TrimPrefix(e.Source.Pointer, "/data")}
+				}
+				validationErr.Errors = append(validationErr.Errors, e)
+			}
+		}
+
+		newObjs = append(newObjs, newObj)
+	}
+
+	if len(httpErr.Errors) > 0 {
+		return httpErr
+	}
+
+	if len(validationErr.Errors) > 0 {
+		return validationErr
+	}
+
+	response, err := source.BulkCreate(newObjs, req)
+	if err != nil {
+		return err
+	}
+
+	switch response.StatusCode() {
+	case http.StatusCreated:
+		return respondWith(response, info, http.StatusCreated, w, r, res.marshalers)
+	case http.StatusNoContent, http.StatusAccepted:
+		w.WriteHeader(response.StatusCode())
+		return nil
+	default:
+		return fmt.Errorf("invalid status code %d from resource %s for method BulkCreate", response.StatusCode(), res.name)
 	}
 }
 
-func (res *resource) handleUpdate(w http.ResponseWriter, r *http.Request, ps httprouter.Params) error {
-	obj, err := res.source.FindOne(ps.ByName("id"), buildRequest(r))
+func (res *resource) handleUpdate(w http.ResponseWriter, r *http.Request, ps httprouter.Params, info information) error {
+	obj, err := res.findOne(ps.ByName("id"), buildRequest(r))
 	if err != nil {
 		return err
 	}
 
-	ctx, err := unmarshalRequest(r, res.marshalers)
-	if err != nil {
+	if err := res.checkPrecondition(obj, info, r); err != nil {
 		return err
 	}
 
-	data, ok := ctx["data"]
-
-	if !ok {
-		return NewHTTPError(
-			errors.New("Forbidden"),
-			"missing mandatory data key.",
-			http.StatusForbidden,
-		)
-	}
-
-	check, ok := data.(map[string]interface{})
-	if !ok {
-		return NewHTTPError(
-			errors.New("Forbidden"),
-			"data must contain an object.",
-			http.StatusForbidden,
-		)
-	}
-
-	if _, ok := check["id"]; !ok {
-		return NewHTTPError(
-			errors.New("Forbidden"),
-			"missing mandatory id key.",
-			http.StatusForbidden,
-		)
-	}
-
-	if _, ok := check["type"]; !ok {
-		return NewHTTPError(
-			errors.New("Forbidden"),
-			"missing mandatory type key.",
-			http.StatusForbidden,
-		)
+	ctx, err := unmarshalRequest(r, res.marshalers)
+	if err != nil {
+		return err
 	}
 
-	updatingObjs := reflect.MakeSlice(reflect.SliceOf(res.resourceType), 1, 1)
-	updatingObjs.Index(0).Set(reflect.ValueOf(obj.Result()))
-
-	structType := res.resourceType
-	if structType.Kind() == reflect.Ptr {
-		structType = structType.Elem()
+	if err := checkDocument(jsonapi.ValidateUpdateRequest(ctx)); err != nil {
+		return err
 	}
 
-	err = jsonapi.UnmarshalInto(ctx, structType, &updatingObjs)
+	updatingObj, err := res.unmarshalUpdate(obj.Result(), ps.ByName("id"), ctx)
 	if err != nil {
 		return err
 	}
-	if updatingObjs.Len() != 1 {
-		return errors.New("expected one object")
-	}
 
-	updatingObj := updatingObjs.Index(0).Interface()
+	req := buildRequest(r)
+	if err := res.validate(updatingObj, req); err != nil {
+		return err
+	}
 
-	response, err := res.source.Update(updatingObj, buildRequest(r))
+	response, err := res.update(updatingObj, req)
 
 	if err != nil {
 		return err
@@ -619,7 +1488,7 @@ func (res *resource) handleUpdate(w http.ResponseWriter, r *http.Request, ps htt
 	case http.StatusOK:
 		updated := response.Result()
 		if updated == nil {
-			internalResponse, err := res.source.FindOne(ps.ByName("id"), buildRequest(r))
+			internalResponse, err := res.findOne(ps.ByName("id"), buildRequest(r))
 			if err != nil {
 				return err
 			}
@@ -631,6 +1500,10 @@ func (res *resource) handleUpdate(w http.ResponseWriter, r *http.Request, ps htt
 			response = internalResponse
 		}
 
+		if err := res.setETag(response, info, w, r); err != nil {
+			return err
+		}
+
 		return respondWith(response, information{}, http.StatusOK, w, r, res.marshalers)
 	case http.StatusAccepted:
 		w.WriteHeader(http.StatusAccepted)
@@ -643,25 +1516,69 @@ func (res *resource) handleUpdate(w http.ResponseWriter, r *http.Request, ps htt
 	}
 }
 
-func (res *resource) handleReplaceRelation(w http.ResponseWriter, r *http.Request, ps httprouter.Params, relation jsonapi.Reference) error {
-	var (
-		err     error
-		editObj interface{}
-	)
+// unmarshalUpdate applies a request document to the existing object with the given id and returns the
+// updated copy
+func (res *resource) unmarshalUpdate(existing interface{}, id string, ctx map[string]interface{}) (interface{}, error) {
+	// the document is validated with jsonapi.ValidateUpdateRequest before
+	if err := res.checkResourceObject(ctx["data"], "/data", id); err != nil {
+		return nil, err
+	}
 
-	response, err := res.source.FindOne(ps.ByName("id"), buildRequest(r))
+	updatingObjs := reflect.MakeSlice(reflect.SliceOf(res.resourceType), 1, 1)
+	updatingObjs.Index(0).Set(reflect.ValueOf(existing))
+
+	structType := res.resourceType
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+
+	err := jsonapi.UnmarshalInto(ctx, structType, &updatingObjs)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if updatingObjs.Len() != 1 {
+		return nil, errors.New("expected one object")
 	}
 
+	return updatingObjs.Index(0).Interface(), nil
+}
+
+func (res *resource) handleReplaceRelation(w http.ResponseWriter, r *http.Request, ps httprouter.Params, relation jsonapi.Reference) error {
 	inc, err := unmarshalRequest(r, res.marshalers)
 	if err != nil {
 		return err
 	}
 
+	if err := checkDocument(jsonapi.ValidateRelationshipRequest(inc)); err != nil {
+		return err
+	}
+
+	err = res.replaceRelation(ps.ByName("id"), relation, inc, buildRequest(r))
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// replaceRelation replaces the complete relationship of the object with the given id
+// with the resource linkage in the data of the request document
+func (res *resource) replaceRelation(id string, relation jsonapi.Reference, inc map[string]interface{}, req Request) error {
+	var editObj interface{}
+
 	data, ok := inc["data"]
 	if !ok {
-		return errors.New("Invalid object. Need a \"data\" object")
+		return newMissingLinkageError()
+	}
+
+	if err := checkLinkage(relation, data, "/data"); err != nil {
+		return err
+	}
+
+	response, err := res.findOne(id, req)
+	if err != nil {
+		return err
 	}
 
 	resType := reflect.TypeOf(response.Result()).Kind()
@@ -677,118 +1594,85 @@ func (res *resource) handleReplaceRelation(w http.ResponseWriter, r *http.Reques
 	}
 
 	if resType == reflect.Struct {
-		_, err = res.source.Update(reflect.ValueOf(editObj).Elem().Interface(), buildRequest(r))
+		_, err = res.update(reflect.ValueOf(editObj).Elem().Interface(), req)
 	} else {
-		_, err = res.source.Update(editObj, buildRequest(r))
+		_, err = res.update(editObj, req)
 	}
 
-	w.WriteHeader(http.StatusNoContent)
 	return err
 }
 
 func (res *resource) handleAddToManyRelation(w http.ResponseWriter, r *http.Request, ps httprouter.Params, relation jsonapi.Reference) error {
-	var (
-		err     error
-		editObj interface{}
-	)
-
-	response, err := res.source.FindOne(ps.ByName("id"), buildRequest(r))
-	if err != nil {
-		return err
-	}
-
 	inc, err := unmarshalRequest(r, res.marshalers)
 	if err != nil {
 		return err
 	}
 
-	data, ok := inc["data"]
-	if !ok {
-		return errors.New("Invalid object. Need a \"data\" object")
+	if err := checkDocument(jsonapi.ValidateRelationshipRequest(inc)); err != nil {
+		return err
 	}
 
-	newRels, ok := data.([]interface{})
-	if !ok {
-		return fmt.Errorf("Data must be an array with \"id\" and \"type\" field to add new to-many relationships")
+	err = res.editToManyRelation(ps.ByName("id"), relation, inc, true, buildRequest(r))
+	if err != nil {
+		return err
 	}
 
-	newIDs := []string{}
-
-	for _, newRel := range newRels {
-		casted, ok := newRel.(map[string]interface{})
-		if !ok {
-			return errors.New("entry in data object invalid")
-		}
-		newID, ok := casted["id"].(string)
-		if !ok {
-			return errors.New("no id field found inside data object")
-		}
-
-		newIDs = append(newIDs, newID)
-	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
 
-	resType := reflect.TypeOf(response.Result()).Kind()
-	if resType == reflect.Struct {
-		editObj = getPointerToStruct(response.Result())
-	} else {
-		editObj = response.Result()
+func (res *resource) handleDeleteToManyRelation(w http.ResponseWriter, r *http.Request, ps httprouter.Params, relation jsonapi.Reference) error {
+	inc, err := unmarshalRequest(r, res.marshalers)
+	if err != nil {
+		return err
 	}
 
-	targetObj, ok := editObj.(jsonapi.EditToManyRelations)
-	if !ok {
-		return errors.New("target struct must implement jsonapi.EditToManyRelations")
+	if err := checkDocument(jsonapi.ValidateRelationshipRequest(inc)); err != nil {
+		return err
 	}
-	targetObj.AddToManyIDs(relation.Name, newIDs)
 
-	if resType == reflect.Struct {
-		_, err = res.source.Update(reflect.ValueOf(targetObj).Elem().Interface(), buildRequest(r))
-	} else {
-		_, err = res.source.Update(targetObj, buildRequest(r))
+	err = res.editToManyRelation(ps.ByName("id"), relation, inc, false, buildRequest(r))
+	if err != nil {
+		return err
 	}
 
 	w.WriteHeader(http.StatusNoContent)
-
-	return err
+	return nil
 }
 
-func (res *resource) handleDeleteToManyRelation(w http.ResponseWriter, r *http.Request, ps httprouter.Params, relation jsonapi.Reference) error {
-	var (
-		err     error
-		editObj interface{}
-	)
-	response, err := res.source.FindOne(ps.ByName("id"), buildRequest(r))
-	if err != nil {
-		return err
-	}
+// editToManyRelation adds or deletes the ids in the data of the request document to or from
+// a to-many relationship of the object with the given id
+func (res *resource) editToManyRelation(id string, relation jsonapi.Reference, inc map[string]interface{}, add bool, req Request) error {
+	var editObj interface{}
 
-	inc, err := unmarshalRequest(r, res.marshalers)
-	if err != nil {
-		return err
+	if !isToMany(relation) {
+		httpErr := NewHTTPError(nil, "Forbidden", http.StatusForbidden)
+		httpErr.Errors = []Error{{
+			Status: strconv.Itoa(http.StatusForbidden),
+			Title:  "Forbidden",
+			Detail: fmt.Sprintf("%s is a to-one relationship, only members of to-many relationships can be added or removed", relation.Name),
+		}}
+		return httpErr
 	}
 
 	data, ok := inc["data"]
 	if !ok {
-		return errors.New("Invalid object. Need a \"data\" object")
+		return newMissingLinkageError()
 	}
 
-	newRels, ok := data.([]interface{})
-	if !ok {
-		return fmt.Errorf("Data must be an array with \"id\" and \"type\" field to add new to-many relationships")
+	// checkLinkage makes sure that data of to-many relationships is an array of resource identifiers
+	if err := checkLinkage(relation, data, "/data"); err != nil {
+		return err
 	}
 
-	obsoleteIDs := []string{}
-
-	for _, newRel := range newRels {
-		casted, ok := newRel.(map[string]interface{})
-		if !ok {
-			return errors.New("entry in data object invalid")
-		}
-		obsoleteID, ok := casted["id"].(string)
-		if !ok {
-			return errors.New("no id field found inside data object")
-		}
+	response, err := res.findOne(id, req)
+	if err != nil {
+		return err
+	}
 
-		obsoleteIDs = append(obsoleteIDs, obsoleteID)
+	ids := []string{}
+	for _, rel := range data.([]interface{}) {
+		ids = append(ids, rel.(map[string]interface{})["id"].(string))
 	}
 
 	resType := reflect.TypeOf(response.Result()).Kind()
@@ -802,15 +1686,18 @@ func (res *resource) handleDeleteToManyRelation(w http.ResponseWriter, r *http.R
 	if !ok {
 		return errors.New("target struct must implement jsonapi.EditToManyRelations")
 	}
-	targetObj.DeleteToManyIDs(relation.Name, obsoleteIDs)
 
-	if resType == reflect.Struct {
-		_, err = res.source.Update(reflect.ValueOf(targetObj).Elem().Interface(), buildRequest(r))
+	if add {
+		targetObj.AddToManyIDs(relation.Name, ids)
 	} else {
-		_, err = res.source.Update(targetObj, buildRequest(r))
+		targetObj.DeleteToManyIDs(relation.Name, ids)
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	if resType == reflect.Struct {
+		_, err = res.update(reflect.ValueOf(targetObj).Elem().Interface(), req)
+	} else {
+		_, err = res.update(targetObj, req)
+	}
 
 	return err
 }
@@ -823,8 +1710,19 @@ func getPointerToStruct(oldObj interface{}) interface{} {
 	return ptr.Interface()
 }
 
-func (res *resource) handleDelete(w http.ResponseWriter, r *http.Request, ps httprouter.Params) error {
-	response, err := res.source.Delete(ps.ByName("id"), buildRequest(r))
+func (res *resource) handleDelete(w http.ResponseWriter, r *http.Request, ps httprouter.Params, info information) error {
+	if res.etags && r.Header.Get("If-Match") != "" {
+		obj, err := res.findOne(ps.ByName("id"), buildRequest(r))
+		if err != nil {
+			return err
+		}
+
+		if err := res.checkPrecondition(obj, info, r); err != nil {
+			return err
+		}
+	}
+
+	response, err := res.delete(ps.ByName("id"), buildRequest(r))
 	if err != nil {
 		return err
 	}
@@ -847,6 +1745,139 @@ func (res *resource) handleDelete(w http.ResponseWriter, r *http.Request, ps htt
 	}
 }
 
+// etag returns the entity tag of a representation of the result of a responder. Models that implement
+// Versioner use their version, combined with the include and fields parameters of r that select the
+// representation, all others a hash of the marshalled document content.
+func (res *resource) etag(response Responder, content []byte, r *http.Request) string {
+	if versioner, ok := response.Result().(Versioner); ok {
+		representation := representationParameters(r)
+		if representation == "" {
+			return strconv.Quote(versioner.Version())
+		}
+
+		return strconv.Quote(fmt.Sprintf("%s-%x", versioner.Version(), sha1.Sum([]byte(representation))))
+	}
+
+	return fmt.Sprintf(`"%x"`, sha1.Sum(content))
+}
+
+// readETag returns the entity tag a read of the result of a responder with the query of r responds with
+func (res *resource) readETag(response Responder, info information, r *http.Request) (string, error) {
+	if _, ok := response.Result().(Versioner); ok {
+		return res.etag(response, nil, r), nil
+	}
+
+	data, err := document(response, info, r)
+	if err != nil {
+		return "", err
+	}
+
+	content, _, err := marshalDocument(data, r, res.marshalers)
+	if err != nil {
+		return "", err
+	}
+
+	return res.etag(response, content, r), nil
+}
+
+// representationParameters returns the query parameters of r that change the representation of the
+// resources in a document, which are include and fields
+func representationParameters(r *http.Request) string {
+	parameters := url.Values{}
+	for key, values := range r.URL.Query() {
+		if key == "include" || strings.HasPrefix(key, "fields[") {
+			parameters[key] = values
+		}
+	}
+
+	return parameters.Encode()
+}
+
+// setETag sets the ETag header for the result of a responder if the resource uses entity tags, so
+// that it can be sent with If-Match in the next update
+func (res *resource) setETag(response Responder, info information, w http.ResponseWriter, r *http.Request) error {
+	if !res.etags || response.Result() == nil {
+		return nil
+	}
+
+	etag, err := res.readETag(response, info, r)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("ETag", etag)
+	return nil
+}
+
+// respondToRead sends the document of a read request. If the resource uses entity tags, it sets the
+// ETag header and responds with 304 Not Modified if it matches If-None-Match.
+func (res *resource) respondToRead(response Responder, data map[string]interface{}, w http.ResponseWriter, r *http.Request) error {
+	content, contentType, err := marshalDocument(data, r, res.marshalers)
+	if err != nil {
+		return err
+	}
+
+	if res.etags && response.Result() != nil {
+		etag := res.etag(response, content, r)
+		w.Header().Set("ETag", etag)
+		if matchesETag(r.Header.Get("If-None-Match"), etag, true) {
+			w.WriteHeader(http.StatusNotModified)
+			return nil
+		}
+	}
+
+	writeResult(w, content, http.StatusOK, contentType)
+	return nil
+}
+
+// checkPrecondition returns 412 Precondition Failed if If-Match does not match the current object
+func (res *resource) checkPrecondition(current Responder, info information, r *http.Request) error {
+	ifMatch := r.Header.Get("If-Match")
+	if !res.etags || ifMatch == "" {
+		return nil
+	}
+
+	etag, err := res.readETag(current, info, r)
+	if err != nil {
+		return err
+	}
+
+	if matchesETag(ifMatch, etag, false) {
+		return nil
+	}
+
+	httpErr := NewHTTPError(nil, "Precondition Failed", http.StatusPreconditionFailed)
+	httpErr.Errors = []Error{{
+		Status: strconv.Itoa(http.StatusPreconditionFailed),
+		Title:  "Precondition Failed",
+		Detail: fmt.Sprintf("%s has been modified, the current entity tag is %s", res.name, etag),
+		Source: &ErrorSource{Parameter: "If-Match"},
+	}}
+
+	return httpErr
+}
+
+// matchesETag returns if etag is in the list of entity tags of an If-Match or If-None-Match header.
+// Weak comparison ignores the W/ prefix of weak entity tags, which never match otherwise.
+func matchesETag(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+
+		if candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
 func writeResult(w http.ResponseWriter, data []byte, status int, contentType string) {
 	w.Header().Set("Content-Type", contentType)
 	w.WriteHeader(status)
@@ -854,32 +1885,59 @@ func writeResult(w http.ResponseWriter, data []byte, status int, contentType str
 }
 
 func respondWith(obj Responder, info information, status int, w http.ResponseWriter, r *http.Request, marshalers map[string]ContentMarshaler) error {
-	data, err := jsonapi.MarshalWithURLs(obj.Result(), info)
+	data, err := document(obj, info, r)
 	if err != nil {
 		return err
 	}
 
+	return marshalResponse(data, w, status, r, marshalers)
+}
+
+// document returns the document with the result and the metadata of a responder
+func document(obj Responder, info information, r *http.Request) (map[string]interface{}, error) {
+	data, err := jsonapi.MarshalWithOptions(obj.Result(), info, marshalOptions(r))
+	if err != nil {
+		return nil, err
+	}
+
 	meta := obj.Metadata()
 	if len(meta) > 0 {
 		data["meta"] = meta
 	}
 
-	return marshalResponse(data, w, status, r, marshalers)
+	return data, nil
 }
 
-func respondWithPagination(obj Responder, info information, status int, links map[string]string, w http.ResponseWriter, r *http.Request, marshalers map[string]ContentMarshaler) error {
-	data, err := jsonapi.MarshalWithURLs(obj.Result(), info)
+func respondWithPagination(obj Responder, info information, status int, links map[string]string, paginationMeta map[string]interface{}, w http.ResponseWriter, r *http.Request, marshalers map[string]ContentMarshaler) error {
+	data, err := paginatedDocument(obj, info, links, paginationMeta, r)
 	if err != nil {
 		return err
 	}
 
+	return marshalResponse(data, w, status, r, marshalers)
+}
+
+// paginatedDocument adds the pagination links and meta to the document, the metadata of
+// the responder takes precedence over the pagination meta
+func paginatedDocument(obj Responder, info information, links map[string]string, paginationMeta map[string]interface{}, r *http.Request) (map[string]interface{}, error) {
+	data, err := jsonapi.MarshalWithOptions(obj.Result(), info, marshalOptions(r))
+	if err != nil {
+		return nil, err
+	}
+
 	data["links"] = links
-	meta := obj.Metadata()
+	meta := map[string]interface{}{}
+	for key, value := range paginationMeta {
+		meta[key] = value
+	}
+	for key, value := range obj.Metadata() {
+		meta[key] = value
+	}
 	if len(meta) > 0 {
 		data["meta"] = meta
 	}
 
-	return marshalResponse(data, w, status, r, marshalers)
+	return data, nil
 }
 
 func unmarshalRequest(r *http.Request, marshalers map[string]ContentMarshaler) (map[string]interface{}, error) {
@@ -894,12 +1952,16 @@ func unmarshalRequest(r *http.Request, marshalers map[string]ContentMarshaler) (
 	if err != nil {
 		return nil, err
 	}
+
+	if err := mediaTypesOf(r).checkMembers(result, r); err != nil {
+		return nil, err
+	}
+
 	return result, nil
 }
 
 func marshalResponse(resp interface{}, w http.ResponseWriter, status int, r *http.Request, marshalers map[string]ContentMarshaler) error {
-	marshaler, contentType := selectContentMarshaler(r, marshalers)
-	result, err := marshaler.Marshal(resp)
+	result, contentType, err := marshalDocument(resp, r, marshalers)
 	if err != nil {
 		return err
 	}
@@ -907,6 +1969,25 @@ func marshalResponse(resp interface{}, w http.ResponseWriter, status int, r *htt
 	return nil
 }
 
+// marshalDocument applies the extensions and profiles to a document and marshals it with the marshaler
+// r accepts, it returns the content and its Content-Type
+func marshalDocument(resp interface{}, r *http.Request, marshalers map[string]ContentMarshaler) ([]byte, string, error) {
+	marshaler, contentType := selectContentMarshaler(r, marshalers)
+	if doc, ok := resp.(map[string]interface{}); ok {
+		params := mediaTypesOf(r).decorate(doc, r)
+		if contentType == defaultContentTypHeader {
+			contentType += params
+		}
+	}
+
+	result, err := marshaler.Marshal(resp)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return result, contentType, nil
+}
+
 func selectContentMarshaler(r *http.Request, marshalers map[string]ContentMarshaler) (marshaler ContentMarshaler, contentType string) {
 	if _, found := r.Header["Accept"]; found {
 		var contentTypes []string
@@ -929,15 +2010,37 @@ func selectContentMarshaler(r *http.Request, marshalers map[string]ContentMarsha
 	return
 }
 
-func handleError(err error, w http.ResponseWriter, r *http.Request, marshalers map[string]ContentMarshaler) {
+// handleError logs err and writes the HTTPError of the error handler of the api, or the one
+// returned by AsHTTPError if there is none
+func (api *API) handleError(err error, w http.ResponseWriter, r *http.Request, route Route, marshalers map[string]ContentMarshaler) {
 	marshaler, contentType := selectContentMarshaler(r, marshalers)
 
-	log.Println(err)
-	if e, ok := err.(HTTPError); ok {
-		writeResult(w, []byte(marshaler.MarshalError(err)), e.status, contentType)
-		return
+	var httpErr HTTPError
+	if api.errorHandler != nil {
+		httpErr = api.errorHandler(err, r, route)
+		// HTTPErrors that are not created with NewHTTPError have no status
+		if httpErr.status == 0 {
+			httpErr.status = AsHTTPError(err).status
+		}
+		if httpErr.status == 0 {
+			httpErr.status = http.StatusInternalServerError
+		}
+	} else {
+		httpErr = AsHTTPError(err)
+	}
 
+	if api.logger != nil {
+		api.logger.Error("request failed",
+			"error", err,
+			"status", httpErr.status,
+			"method", r.Method,
+			"path", r.URL.Path,
+			"resource", route.Resource,
+			"operation", string(route.Operation),
+		)
+	} else {
+		log.Println(err)
 	}
 
-	writeResult(w, []byte(marshaler.MarshalError(err)), http.StatusInternalServerError, contentType)
+	writeResult(w, []byte(marshaler.MarshalError(httpErr)), httpErr.status, contentType)
 }
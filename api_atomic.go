@@ -0,0 +1,483 @@
+package api2go
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/manyminds/api2go/jsonapi"
+)
+
+const atomicExtension = "https://jsonapi.org/ext/atomic"
+
+// EnableAtomicOperations registers the endpoint of the atomic operations extension
+// (https://jsonapi.org/ext/atomic) under prefix + "operations".
+//
+// All operations of a request are dispatched in order to the sources of the registered
+// resources. Local ids (lid) of added resources can be used by subsequent operations of
+// the same request. If transactor is not nil, all operations run inside one transaction
+// which is rolled back if any of them fails.
+func (api *API) EnableAtomicOperations(transactor Transactor) {
+	api.RegisterExtension(atomicOperations{})
+
+	res := &resource{name: "operations", marshalers: api.marshalers}
+	route := Route{Resource: res.name, Operation: OperationAtomic}
+
+	api.route("POST", api.prefix+res.name, api.handle(res, route, func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) error {
+		return api.handleAtomicOperations(w, r, transactor)
+	}))
+}
+
+func (api *API) handleAtomicOperations(w http.ResponseWriter, r *http.Request, transactor Transactor) error {
+	doc, err := unmarshalRequest(r, api.marshalers)
+	if err != nil {
+		return err
+	}
+
+	operations, ok := doc["atomic:operations"].([]interface{})
+	if !ok {
+		return newPointerError(http.StatusBadRequest, "Invalid operations", "atomic:operations must be an array of operation objects", "/atomic:operations")
+	}
+
+	req := buildRequest(r)
+	if transactor != nil {
+		ctx, err := transactor.Begin(req.Context())
+		if err != nil {
+			return err
+		}
+
+		req = req.WithContext(ctx)
+	}
+
+	results, err := api.runAtomicOperations(operations, req)
+	if transactor != nil {
+		if err != nil {
+			if rollbackErr := transactor.Rollback(req.Context()); rollbackErr != nil {
+				return rollbackErr
+			}
+		} else {
+			err = transactor.Commit(req.Context())
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	hasData := false
+	for _, result := range results {
+		if len(result) > 0 {
+			hasData = true
+			break
+		}
+	}
+
+	if !hasData {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+
+	// the atomic:results member adds the atomic extension to the ext parameter of the Content-Type
+	return marshalResponse(map[string]interface{}{"atomic:results": results}, w, http.StatusOK, r, api.marshalers)
+}
+
+// atomicOperations is the extension of the atomic operations endpoint, which handles its members itself
+type atomicOperations struct{}
+
+func (atomicOperations) URI() string {
+	return atomicExtension
+}
+
+func (atomicOperations) Namespace() string {
+	return "atomic"
+}
+
+func (atomicOperations) ValidateMember(name string, value interface{}) error {
+	return nil
+}
+
+func (atomicOperations) Decorate(document map[string]interface{}, r *http.Request) {}
+
+// runAtomicOperations runs all operations in order and stops at the first failing one
+func (api *API) runAtomicOperations(operations []interface{}, req Request) ([]map[string]interface{}, error) {
+	results := make([]map[string]interface{}, 0, len(operations))
+	lids := map[string]string{}
+
+	for i, entry := range operations {
+		pointer := fmt.Sprintf("/atomic:operations/%d", i)
+
+		operation, ok := entry.(map[string]interface{})
+		if !ok {
+			return nil, newPointerError(http.StatusBadRequest, "Invalid operation", "operation must be an object", pointer)
+		}
+
+		result, err := api.runAtomicOperation(operation, lids, req)
+		if err != nil {
+			return nil, prefixAtomicError(err, pointer)
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func (api *API) runAtomicOperation(operation map[string]interface{}, lids map[string]string, req Request) (map[string]interface{}, error) {
+	op, _ := operation["op"].(string)
+	ref, hasRef := operation["ref"].(map[string]interface{})
+	data, hasData := operation["data"]
+
+	// local ids of the primary data of add operations are assigned by the operation itself
+	if op == "add" && !hasRef {
+		if object, ok := data.(map[string]interface{}); ok {
+			if relationships, ok := object["relationships"]; ok {
+				resolveLocalIDs(relationships, lids)
+			}
+		}
+	} else {
+		resolveLocalIDs(ref, lids)
+		resolveLocalIDs(data, lids)
+	}
+
+	if hasData {
+		doc := map[string]interface{}{"data": data}
+		violations := jsonapi.ValidateRequest(doc)
+		if _, ok := ref["relationship"]; ok {
+			violations = jsonapi.ValidateRelationshipRequest(doc)
+		} else if op == "update" {
+			violations = jsonapi.ValidateUpdateRequest(doc)
+		}
+
+		if err := checkDocument(violations); err != nil {
+			return nil, err
+		}
+	}
+
+	target := ref
+	targetPointer := "/ref"
+	if !hasRef {
+		object, ok := data.(map[string]interface{})
+		if !ok {
+			return nil, newPointerError(http.StatusBadRequest, "Invalid operation", "operation must contain a ref or a data object", "")
+		}
+
+		target = object
+		targetPointer = "/data"
+	}
+
+	name, _ := target["type"].(string)
+	res := api.resourceByName(name)
+	if res == nil {
+		return nil, newPointerError(http.StatusNotFound, "Unknown resource", fmt.Sprintf("resource type %q is not registered", name), targetPointer+"/type")
+	}
+
+	// apart from adding a new resource, every operation targets an existing one
+	id, hasID := target["id"].(string)
+	if !hasID && (op != "add" || hasRef) {
+		if lid, ok := target["lid"].(string); ok {
+			return nil, newPointerError(http.StatusBadRequest, "Unknown local id", fmt.Sprintf("lid %q does not reference a resource added before", lid), targetPointer+"/lid")
+		}
+
+		return nil, newPointerError(http.StatusBadRequest, "Invalid operation", "operation must reference a resource by id or lid", targetPointer)
+	}
+
+	if relationship, ok := target["relationship"].(string); ok && hasRef {
+		relation, found := res.reference(relationship)
+		if !found {
+			return nil, newPointerError(http.StatusNotFound, "Unknown relationship", fmt.Sprintf("%s has no relationship %s", res.name, relationship), "/ref/relationship")
+		}
+		if !hasData {
+			return nil, newPointerError(http.StatusBadRequest, "Invalid operation", "relationship operations must contain data", "")
+		}
+
+		if !res.canUpdate() {
+			return nil, newMethodNotAllowedError(res.name + " can not be updated")
+		}
+
+		inc := map[string]interface{}{"data": data}
+		route := Route{Resource: res.name, Operation: OperationRelationship, Relation: relation.Name}
+		switch op {
+		case "update":
+			return api.runResourceMiddlewares(res, route, req, func(req Request) (map[string]interface{}, error) {
+				return map[string]interface{}{}, res.replaceRelation(id, relation, inc, req)
+			})
+		case "add", "remove":
+			return api.runResourceMiddlewares(res, route, req, func(req Request) (map[string]interface{}, error) {
+				return map[string]interface{}{}, res.editToManyRelation(id, relation, inc, op == "add", req)
+			})
+		}
+	} else {
+		switch op {
+		case "add":
+			return api.runResourceMiddlewares(res, Route{Resource: res.name, Operation: OperationCreate}, req, func(req Request) (map[string]interface{}, error) {
+				return api.atomicAdd(res, operation, lids, req)
+			})
+		case "update":
+			return api.runResourceMiddlewares(res, Route{Resource: res.name, Operation: OperationUpdate}, req, func(req Request) (map[string]interface{}, error) {
+				return api.atomicUpdate(res, id, operation, req)
+			})
+		case "remove":
+			return api.runResourceMiddlewares(res, Route{Resource: res.name, Operation: OperationDelete}, req, func(req Request) (map[string]interface{}, error) {
+				return api.atomicRemove(res, id, req)
+			})
+		}
+	}
+
+	return nil, newPointerError(http.StatusBadRequest, "Invalid operation", fmt.Sprintf("unknown op %q", op), "/op")
+}
+
+// runResourceMiddlewares runs an operation behind the middlewares of its resource. As the response
+// of the atomic request is written once for all operations, a middleware that does not call next
+// rejects the operation with the status it wrote or with 403 Forbidden.
+func (api *API) runResourceMiddlewares(res *resource, route Route, req Request, operation func(Request) (map[string]interface{}, error)) (map[string]interface{}, error) {
+	if len(res.middlewares) == 0 {
+		return operation(req)
+	}
+
+	var (
+		result map[string]interface{}
+		err    error
+		called bool
+	)
+
+	chain := func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		result, err = operation(req.WithContext(r.Context()))
+	}
+
+	for i := len(res.middlewares) - 1; i >= 0; i-- {
+		middleware, next := res.middlewares[i], chain
+		chain = func(w http.ResponseWriter, r *http.Request) {
+			middleware(w, r, route, next)
+		}
+	}
+
+	w := &discardResponseWriter{header: http.Header{}}
+	chain(w, req.PlainRequest.WithContext(req.Context()))
+	if !called {
+		status := w.status
+		if status < http.StatusBadRequest {
+			status = http.StatusForbidden
+		}
+
+		return nil, newPointerError(status, http.StatusText(status), fmt.Sprintf("the operation was rejected by a middleware of %s", res.name), "")
+	}
+
+	return result, err
+}
+
+// discardResponseWriter records the status written by resource middlewares of atomic operations
+type discardResponseWriter struct {
+	header http.Header
+	status int
+}
+
+func (w *discardResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *discardResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	return len(b), nil
+}
+
+func (w *discardResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (api *API) atomicAdd(res *resource, operation map[string]interface{}, lids map[string]string, req Request) (map[string]interface{}, error) {
+	data, pointer, err := singleResourceObject(operation["data"])
+	if err != nil {
+		return nil, err
+	}
+
+	if err := res.checkResourceObject(data, pointer, ""); err != nil {
+		return nil, err
+	}
+
+	if err := res.checkClientID(data, pointer); err != nil {
+		return nil, err
+	}
+
+	newObj, err := res.unmarshalNew(operation)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := res.validate(newObj, req); err != nil {
+		return nil, err
+	}
+
+	response, err := res.create(newObj, req)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := res.createdID(newObj, response)
+	if err != nil {
+		return nil, err
+	}
+
+	if object, ok := operation["data"].(map[string]interface{}); ok {
+		if lid, ok := object["lid"].(string); ok {
+			lids[lid] = id
+		}
+	}
+
+	switch response.StatusCode() {
+	case http.StatusCreated:
+		return api.atomicResult(response)
+	case http.StatusNoContent, http.StatusAccepted:
+		return map[string]interface{}{}, nil
+	default:
+		return nil, fmt.Errorf("invalid status code %d from resource %s for method Create", response.StatusCode(), res.name)
+	}
+}
+
+func (api *API) atomicUpdate(res *resource, id string, operation map[string]interface{}, req Request) (map[string]interface{}, error) {
+	if !res.canUpdate() {
+		return nil, newMethodNotAllowedError(res.name + " can not be updated")
+	}
+
+	obj, err := res.findOne(id, req)
+	if err != nil {
+		return nil, err
+	}
+
+	updatingObj, err := res.unmarshalUpdate(obj.Result(), id, operation)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := res.validate(updatingObj, req); err != nil {
+		return nil, err
+	}
+
+	response, err := res.update(updatingObj, req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch response.StatusCode() {
+	case http.StatusOK:
+		if response.Result() == nil {
+			response, err = res.findOne(id, req)
+			if err != nil {
+				return nil, err
+			}
+			if response.Result() == nil {
+				return nil, fmt.Errorf("Expected FindOne to return one object of resource %s", res.name)
+			}
+		}
+
+		return api.atomicResult(response)
+	case http.StatusNoContent, http.StatusAccepted:
+		return map[string]interface{}{}, nil
+	default:
+		return nil, fmt.Errorf("invalid status code %d from resource %s for method Update", response.StatusCode(), res.name)
+	}
+}
+
+func (api *API) atomicRemove(res *resource, id string, req Request) (map[string]interface{}, error) {
+	response, err := res.delete(id, req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch response.StatusCode() {
+	case http.StatusOK:
+		if meta := response.Metadata(); len(meta) > 0 {
+			return map[string]interface{}{"meta": meta}, nil
+		}
+
+		return map[string]interface{}{}, nil
+	case http.StatusNoContent, http.StatusAccepted:
+		return map[string]interface{}{}, nil
+	default:
+		return nil, fmt.Errorf("invalid status code %d from resource %s for method Delete", response.StatusCode(), res.name)
+	}
+}
+
+// atomicResult marshals the result of a responder into an atomic result object
+func (api *API) atomicResult(response Responder) (map[string]interface{}, error) {
+	marshalled, err := jsonapi.MarshalWithOptions(response.Result(), api.info, jsonapi.MarshalOptions{Include: []string{}})
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]interface{}{"data": marshalled["data"]}
+	if meta := response.Metadata(); len(meta) > 0 {
+		result["meta"] = meta
+	}
+
+	return result, nil
+}
+
+func (api *API) resourceByName(name string) *resource {
+	for i := range api.resources {
+		if api.resources[i].name == name {
+			return &api.resources[i]
+		}
+	}
+
+	return nil
+}
+
+func (res *resource) reference(name string) (jsonapi.Reference, bool) {
+	for _, reference := range res.references() {
+		if reference.Name == name {
+			return reference, true
+		}
+	}
+
+	return jsonapi.Reference{}, false
+}
+
+// resolveLocalIDs replaces all known local ids in resource identifier objects with the ids
+// the server assigned to the added resources
+func resolveLocalIDs(value interface{}, lids map[string]string) {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		if lid, ok := value["lid"].(string); ok {
+			if id, known := lids[lid]; known {
+				value["id"] = id
+				delete(value, "lid")
+			}
+		}
+
+		for _, element := range value {
+			resolveLocalIDs(element, lids)
+		}
+	case []interface{}:
+		for _, element := range value {
+			resolveLocalIDs(element, lids)
+		}
+	}
+}
+
+// prefixAtomicError makes the pointers of all errors of a failed operation relative to
+// the request document by prefixing them with the pointer of the operation
+func prefixAtomicError(err error, prefix string) error {
+	httpErr := AsHTTPError(err)
+
+	errs := make([]Error, len(httpErr.Errors))
+	for i, e := range httpErr.Errors {
+		source := ErrorSource{}
+		if e.Source != nil {
+			source = *e.Source
+		}
+		if source.Parameter == "" {
+			source.Pointer = prefix + source.Pointer
+		}
+
+		e.Source = &source
+		errs[i] = e
+	}
+	httpErr.Errors = errs
+
+	return httpErr
+}
@@ -0,0 +1,223 @@
+package api2go
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type transactionKey struct{}
+
+type recordingTransactor struct {
+	calls []string
+}
+
+func (t *recordingTransactor) Begin(ctx context.Context) (context.Context, error) {
+	t.calls = append(t.calls, "begin")
+	return context.WithValue(ctx, transactionKey{}, "tx"), nil
+}
+
+func (t *recordingTransactor) Commit(ctx context.Context) error {
+	t.calls = append(t.calls, "commit "+ctx.Value(transactionKey{}).(string))
+	return nil
+}
+
+func (t *recordingTransactor) Rollback(ctx context.Context) error {
+	t.calls = append(t.calls, "rollback "+ctx.Value(transactionKey{}).(string))
+	return nil
+}
+
+type transactionSource struct {
+	fixtureSource
+	transactions []interface{}
+}
+
+func (s *transactionSource) CreateContext(ctx context.Context, obj interface{}, req Request) (Responder, error) {
+	s.transactions = append(s.transactions, ctx.Value(transactionKey{}))
+	return s.Create(obj, req)
+}
+
+var _ = Describe("Atomic operations", func() {
+	var (
+		api        *API
+		rec        *httptest.ResponseRecorder
+		source     *transactionSource
+		transactor *recordingTransactor
+	)
+
+	BeforeEach(func() {
+		source = &transactionSource{fixtureSource: fixtureSource{posts: map[string]*Post{
+			"1": {ID: "1", Title: "Hello, World!"},
+			"2": {ID: "2", Title: "I am NR. 2"},
+		}}}
+		transactor = &recordingTransactor{}
+		api = NewAPI("v1")
+		api.AddResource(Post{}, source)
+		api.EnableAtomicOperations(transactor)
+		rec = httptest.NewRecorder()
+	})
+
+	doRequest := func(body string) {
+		req, err := http.NewRequest("POST", "/v1/operations", strings.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+		req.Header.Set("Content-Type", `application/vnd.api+json; ext="https://jsonapi.org/ext/atomic"`)
+		api.Handler().ServeHTTP(rec, req)
+	}
+
+	It("runs all operations in order and returns their results", func() {
+		doRequest(`{"atomic:operations": [
+			{"op": "add", "data": {"type": "posts", "lid": "new", "attributes": {"title": "New"}}},
+			{"op": "update", "data": {"type": "posts", "lid": "new", "attributes": {"title": "Updated"}}},
+			{"op": "add", "ref": {"type": "posts", "lid": "new", "relationship": "comments"}, "data": [{"type": "comments", "id": "1"}]},
+			{"op": "update", "ref": {"type": "posts", "id": "1", "relationship": "author"}, "data": {"type": "users", "id": "1"}},
+			{"op": "remove", "ref": {"type": "posts", "id": "2"}}
+		]}`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("Content-Type")).To(Equal(`application/vnd.api+json; ext="https://jsonapi.org/ext/atomic"`))
+
+		Expect(source.posts).To(HaveLen(2))
+		Expect(source.posts["3"].Title).To(Equal("Updated"))
+		Expect(source.posts["3"].Comments).To(Equal([]Comment{{ID: "1"}}))
+		Expect(source.posts["1"].Author).To(Equal(&User{ID: "1"}))
+
+		var result map[string][]map[string]interface{}
+		Expect(json.Unmarshal(rec.Body.Bytes(), &result)).To(Succeed())
+		Expect(result["atomic:results"]).To(HaveLen(5))
+		Expect(result["atomic:results"][0]["data"]).To(HaveKeyWithValue("id", "3"))
+		Expect(result["atomic:results"][1]).To(BeEmpty())
+		Expect(result["atomic:results"][4]).To(BeEmpty())
+	})
+
+	It("runs all operations in one transaction", func() {
+		doRequest(`{"atomic:operations": [
+			{"op": "add", "data": {"type": "posts", "attributes": {"title": "New"}}}
+		]}`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(source.transactions).To(Equal([]interface{}{"tx"}))
+		Expect(transactor.calls).To(Equal([]string{"begin", "commit tx"}))
+	})
+
+	It("responds with no content if no operation has a result", func() {
+		doRequest(`{"atomic:operations": [{"op": "remove", "ref": {"type": "posts", "id": "1"}}]}`)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(source.posts).To(HaveLen(1))
+	})
+
+	It("rolls back and points to the failed operation", func() {
+		doRequest(`{"atomic:operations": [
+			{"op": "add", "data": {"type": "posts", "attributes": {"title": "New"}}},
+			{"op": "update", "data": {"type": "posts", "id": "42", "attributes": {"title": "Updated"}}}
+		]}`)
+		Expect(rec.Code).To(Equal(http.StatusNotFound))
+		Expect(transactor.calls).To(Equal([]string{"begin", "rollback tx"}))
+		Expect(rec.Body.Bytes()).To(MatchJSON(`{"errors": [
+			{"status": "404", "title": "post not found", "source": {"pointer": "/atomic:operations/1"}}
+		]}`))
+	})
+
+	It("rejects adding to a to-one relationship", func() {
+		doRequest(`{"atomic:operations": [
+			{"op": "add", "ref": {"type": "posts", "id": "1", "relationship": "author"}, "data": null}
+		]}`)
+		Expect(rec.Code).To(Equal(http.StatusForbidden))
+		Expect(rec.Body.Bytes()).To(MatchJSON(`{"errors": [{
+			"status": "403",
+			"title": "Forbidden",
+			"detail": "author is a to-one relationship, only members of to-many relationships can be added or removed",
+			"source": {"pointer": "/atomic:operations/0"}
+		}]}`))
+	})
+
+	It("rejects removing from a to-one relationship", func() {
+		doRequest(`{"atomic:operations": [
+			{"op": "remove", "ref": {"type": "posts", "id": "1", "relationship": "author"}, "data": {"type": "users", "id": "1"}}
+		]}`)
+		Expect(rec.Code).To(Equal(http.StatusForbidden))
+		Expect(source.posts["1"].Author).To(BeNil())
+	})
+
+	It("rejects unknown local ids", func() {
+		doRequest(`{"atomic:operations": [
+			{"op": "remove", "ref": {"type": "posts", "lid": "unknown"}}
+		]}`)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(rec.Body.Bytes()).To(MatchJSON(`{"errors": [{
+			"status": "400",
+			"title": "Unknown local id",
+			"detail": "lid \"unknown\" does not reference a resource added before",
+			"source": {"pointer": "/atomic:operations/0/ref/lid"}
+		}]}`))
+	})
+
+	It("rejects unknown resource types", func() {
+		doRequest(`{"atomic:operations": [
+			{"op": "remove", "ref": {"type": "unicorns", "id": "1"}}
+		]}`)
+		Expect(rec.Code).To(Equal(http.StatusNotFound))
+		Expect(rec.Body.String()).To(ContainSubstring(`"pointer":"/atomic:operations/0/ref/type"`))
+	})
+
+	It("rejects unknown ops", func() {
+		doRequest(`{"atomic:operations": [
+			{"op": "upsert", "data": {"type": "posts", "id": "1"}}
+		]}`)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(rec.Body.String()).To(ContainSubstring(`"pointer":"/atomic:operations/0/op"`))
+	})
+
+	It("rejects documents without operations", func() {
+		doRequest(`{"data": {"type": "posts", "id": "1"}}`)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(rec.Body.String()).To(ContainSubstring(`"pointer":"/atomic:operations"`))
+	})
+
+	It("runs the middlewares of the api", func() {
+		var routes []Route
+		api.Use(func(w http.ResponseWriter, r *http.Request, route Route, next http.HandlerFunc) {
+			routes = append(routes, route)
+			next(w, r)
+		})
+		doRequest(`{"atomic:operations": []}`)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(routes).To(Equal([]Route{{Resource: "operations", Operation: OperationAtomic}}))
+	})
+
+	It("runs the middlewares of the resources for each operation", func() {
+		var routes []Route
+		api = NewAPI("v1")
+		api.AddResource(Post{}, source, WithMiddleware(func(w http.ResponseWriter, r *http.Request, route Route, next http.HandlerFunc) {
+			routes = append(routes, route)
+			if route.Operation == OperationDelete {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			next(w, r)
+		}))
+		api.EnableAtomicOperations(transactor)
+
+		doRequest(`{"atomic:operations": [
+			{"op": "add", "data": {"type": "posts", "attributes": {"title": "New"}}},
+			{"op": "add", "ref": {"type": "posts", "id": "1", "relationship": "comments"}, "data": [{"type": "comments", "id": "1"}]},
+			{"op": "remove", "ref": {"type": "posts", "id": "2"}}
+		]}`)
+		Expect(rec.Code).To(Equal(http.StatusForbidden))
+		Expect(rec.Body.Bytes()).To(MatchJSON(`{"errors": [{
+			"status": "403",
+			"title": "Forbidden",
+			"detail": "the operation was rejected by a middleware of posts",
+			"source": {"pointer": "/atomic:operations/2"}
+		}]}`))
+		Expect(routes).To(Equal([]Route{
+			{Resource: "posts", Operation: OperationCreate},
+			{Resource: "posts", Operation: OperationRelationship, Relation: "comments"},
+			{Resource: "posts", Operation: OperationDelete},
+		}))
+		Expect(source.posts).To(HaveKey("2"))
+		Expect(transactor.calls).To(Equal([]string{"begin", "rollback tx"}))
+	})
+})
@@ -0,0 +1,124 @@
+package api2go
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type bulkSource struct {
+	fixtureSource
+}
+
+// BulkCreate creates either all posts or none of them
+func (s *bulkSource) BulkCreate(objs []interface{}, req Request) (Responder, error) {
+	httpErr := NewHTTPError(nil, "Invalid posts", http.StatusUnprocessableEntity)
+	posts := []Post{}
+	for i, obj := range objs {
+		post := obj.(Post)
+		if post.Title == "" {
+			httpErr.Errors = append(httpErr.Errors, Error{
+				Title:  "title must not be empty",
+				Source: &ErrorSource{Pointer: fmt.Sprintf("/data/%d/attributes/title", i)},
+			})
+		}
+		posts = append(posts, post)
+	}
+
+	if len(httpErr.Errors) > 0 {
+		return &Response{}, httpErr
+	}
+
+	for i := range posts {
+		posts[i].ID = strconv.Itoa(len(s.posts) + 1)
+		s.posts[posts[i].ID] = &posts[i]
+	}
+
+	return &Response{Res: posts, Code: http.StatusCreated}, nil
+}
+
+var _ = Describe("Bulk create", func() {
+	var (
+		api    *API
+		rec    *httptest.ResponseRecorder
+		source *bulkSource
+	)
+
+	BeforeEach(func() {
+		source = &bulkSource{fixtureSource: fixtureSource{posts: map[string]*Post{
+			"1": {ID: "1", Title: "Hello, World!"},
+		}}}
+		api = NewAPI("v1")
+		api.AddResource(Post{}, source)
+		rec = httptest.NewRecorder()
+	})
+
+	doRequest := func(body string) {
+		req, err := http.NewRequest("POST", "/v1/posts", strings.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+	}
+
+	It("creates all objects in one call", func() {
+		doRequest(`{"data": [
+			{"type": "posts", "attributes": {"title": "First"}},
+			{"type": "posts", "attributes": {"title": "Second"}}
+		]}`)
+		Expect(rec.Code).To(Equal(http.StatusCreated))
+		Expect(source.posts).To(HaveLen(3))
+
+		var result map[string]interface{}
+		Expect(json.Unmarshal(rec.Body.Bytes(), &result)).To(Succeed())
+		Expect(result["data"]).To(HaveLen(2))
+	})
+
+	It("still creates single objects with Create", func() {
+		doRequest(`{"data": {"type": "posts", "attributes": {"title": "First"}}}`)
+		Expect(rec.Code).To(Equal(http.StatusCreated))
+		Expect(rec.Header().Get("Location")).To(Equal("/v1/posts/2"))
+	})
+
+	It("creates nothing if one object is invalid", func() {
+		doRequest(`{"data": [
+			{"type": "posts", "attributes": {"title": "First"}},
+			{"type": "posts", "attributes": {"title": ""}}
+		]}`)
+		Expect(rec.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(source.posts).To(HaveLen(1))
+		Expect(rec.Body.Bytes()).To(MatchJSON(`{"errors": [
+			{"title": "title must not be empty", "source": {"pointer": "/data/1/attributes/title"}}
+		]}`))
+	})
+
+	It("reports objects that can not be unmarshalled with their position", func() {
+		doRequest(`{"data": [
+			{"type": "posts", "attributes": {"title": "First"}},
+			{"type": "posts", "attributes": {"title": 2}},
+			{"type": "posts", "attributes": {"unicorn": "Third"}}
+		]}`)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(source.posts).To(HaveLen(1))
+
+		var result HTTPError
+		Expect(json.Unmarshal(rec.Body.Bytes(), &result)).To(Succeed())
+		Expect(result.Errors).To(HaveLen(2))
+		Expect(result.Errors[0].Source.Pointer).To(Equal("/data/1"))
+		Expect(result.Errors[1].Source.Pointer).To(Equal("/data/2"))
+	})
+
+	It("responds with 409 Conflict to objects of another type", func() {
+		doRequest(`{"data": [
+			{"type": "posts", "attributes": {"title": "First"}},
+			{"type": "comments", "attributes": {"value": "Second"}}
+		]}`)
+		Expect(rec.Code).To(Equal(http.StatusConflict))
+		Expect(rec.Body.String()).To(ContainSubstring(`"pointer":"/data/1/type"`))
+		Expect(source.posts).To(HaveLen(1))
+	})
+})
@@ -0,0 +1,170 @@
+package api2go
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// clientIDSource stores posts with client-generated ids as they are and responds with 204 No Content
+type clientIDSource struct {
+	*fixtureSource
+}
+
+func (s clientIDSource) Create(obj interface{}, req Request) (Responder, error) {
+	p := obj.(Post)
+	if p.ID == "" {
+		return s.fixtureSource.Create(obj, req)
+	}
+
+	if _, exists := s.posts[p.ID]; exists {
+		return nil, fmt.Errorf("post %s: %w", p.ID, ErrDuplicateID)
+	}
+
+	s.posts[p.ID] = &p
+	return &Response{Code: http.StatusNoContent}, nil
+}
+
+var _ = Describe("Client-generated ids", func() {
+	var (
+		api    *API
+		rec    *httptest.ResponseRecorder
+		source *fixtureSource
+	)
+
+	BeforeEach(func() {
+		source = &fixtureSource{posts: map[string]*Post{"1": {ID: "1", Title: "Hello, World!"}}}
+		api = NewAPI("v1")
+		rec = httptest.NewRecorder()
+	})
+
+	doRequest := func(url, body string) {
+		req, err := http.NewRequest("POST", url, strings.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+	}
+
+	Context("allowed by default", func() {
+		BeforeEach(func() {
+			api.AddResource(Post{}, clientIDSource{source})
+		})
+
+		It("creates resources with the id of the client", func() {
+			doRequest("/v1/posts", `{"data": {"type": "posts", "id": "abc", "attributes": {"title": "New"}}}`)
+			Expect(rec.Code).To(Equal(http.StatusNoContent))
+			Expect(rec.Header().Get("Location")).To(Equal("/v1/posts/abc"))
+			Expect(rec.Body.String()).To(BeEmpty())
+			Expect(source.posts).To(HaveKey("abc"))
+		})
+
+		It("creates resources without id", func() {
+			doRequest("/v1/posts", `{"data": {"type": "posts", "attributes": {"title": "New"}}}`)
+			Expect(rec.Code).To(Equal(http.StatusCreated))
+			Expect(rec.Header().Get("Location")).To(Equal("/v1/posts/2"))
+		})
+
+		It("responds with 409 Conflict to duplicate ids", func() {
+			doRequest("/v1/posts", `{"data": {"type": "posts", "id": "1", "attributes": {"title": "New"}}}`)
+			Expect(rec.Code).To(Equal(http.StatusConflict))
+			Expect(rec.Body.Bytes()).To(MatchJSON(`{"errors": [{
+				"status": "409",
+				"title": "Duplicate id",
+				"detail": "posts with id 1 already exists",
+				"source": {"pointer": "/data/id"}
+			}]}`))
+			Expect(source.posts["1"].Title).To(Equal("Hello, World!"))
+		})
+	})
+
+	Context("forbidden", func() {
+		BeforeEach(func() {
+			api.AddResource(Post{}, clientIDSource{source}, WithClientIDs(ClientIDsForbidden))
+			api.EnableAtomicOperations(nil)
+		})
+
+		It("responds with 403 Forbidden to ids of the client", func() {
+			doRequest("/v1/posts", `{"data": {"type": "posts", "id": "abc", "attributes": {"title": "New"}}}`)
+			Expect(rec.Code).To(Equal(http.StatusForbidden))
+			Expect(rec.Body.Bytes()).To(MatchJSON(`{"errors": [{
+				"status": "403",
+				"title": "Client-generated id not allowed",
+				"detail": "posts does not accept ids generated by clients",
+				"source": {"pointer": "/data/id"}
+			}]}`))
+			Expect(source.posts).ToNot(HaveKey("abc"))
+		})
+
+		It("responds with 403 Forbidden to ids of the client in an array with one element", func() {
+			doRequest("/v1/posts", `{"data": [{"type": "posts", "id": "abc", "attributes": {"title": "New"}}]}`)
+			Expect(rec.Code).To(Equal(http.StatusForbidden))
+			Expect(rec.Body.String()).To(ContainSubstring(`"pointer":"/data/0/id"`))
+			Expect(source.posts).ToNot(HaveKey("abc"))
+		})
+
+		It("responds with 400 Bad Request to arrays with multiple resource objects", func() {
+			doRequest("/v1/posts", `{"data": [{"type": "posts", "attributes": {"title": "A"}}, {"type": "posts", "attributes": {"title": "B"}}]}`)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.Bytes()).To(MatchJSON(`{"errors": [{
+				"status": "400",
+				"title": "Invalid document",
+				"detail": "data must contain one resource object",
+				"source": {"pointer": "/data"}
+			}]}`))
+		})
+
+		It("creates resources without id", func() {
+			doRequest("/v1/posts", `{"data": {"type": "posts", "attributes": {"title": "New"}}}`)
+			Expect(rec.Code).To(Equal(http.StatusCreated))
+		})
+
+		It("rejects ids of the client in atomic operations", func() {
+			doRequest("/v1/operations", `{"atomic:operations": [
+				{"op": "add", "data": {"type": "posts", "id": "abc", "attributes": {"title": "New"}}}
+			]}`)
+			Expect(rec.Code).To(Equal(http.StatusForbidden))
+			Expect(rec.Body.String()).To(ContainSubstring(`"pointer":"/atomic:operations/0/data/id"`))
+		})
+	})
+
+	Context("required", func() {
+		BeforeEach(func() {
+			api.AddResource(Post{}, clientIDSource{source}, WithClientIDs(ClientIDsRequired))
+		})
+
+		It("creates resources with the id of the client", func() {
+			doRequest("/v1/posts", `{"data": {"type": "posts", "id": "abc", "attributes": {"title": "New"}}}`)
+			Expect(rec.Code).To(Equal(http.StatusNoContent))
+		})
+
+		It("responds with 422 Unprocessable Entity to missing ids", func() {
+			doRequest("/v1/posts", `{"data": {"type": "posts", "attributes": {"title": "New"}}}`)
+			Expect(rec.Code).To(Equal(http.StatusUnprocessableEntity))
+			Expect(rec.Body.Bytes()).To(MatchJSON(`{"errors": [{
+				"status": "422",
+				"title": "Missing id",
+				"detail": "posts must be created with an id generated by the client",
+				"source": {"pointer": "/data"}
+			}]}`))
+			Expect(source.posts).To(HaveLen(1))
+		})
+	})
+
+	It("does not accept 204 No Content without id of the client", func() {
+		api.AddResource(Post{}, noContentSource{source})
+		doRequest("/v1/posts", `{"data": {"type": "posts", "attributes": {"title": "New"}}}`)
+		Expect(rec.Code).To(Equal(http.StatusInternalServerError))
+	})
+})
+
+// noContentSource responds with 204 No Content to every create request
+type noContentSource struct {
+	*fixtureSource
+}
+
+func (s noContentSource) Create(obj interface{}, req Request) (Responder, error) {
+	return &Response{Code: http.StatusNoContent}, nil
+}
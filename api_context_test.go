@@ -0,0 +1,113 @@
+package api2go
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type contextKey string
+
+type contextSource struct {
+	fixtureSource
+	values []interface{}
+}
+
+func (s *contextSource) FindOneContext(ctx context.Context, ID string, req Request) (Responder, error) {
+	s.values = append(s.values, ctx.Value(contextKey("user")))
+	return s.FindOne(ID, req)
+}
+
+func (s *contextSource) FindAllContext(ctx context.Context, req Request) (Responder, error) {
+	s.values = append(s.values, ctx.Value(contextKey("user")))
+	return s.FindAll(req)
+}
+
+func (s *contextSource) PaginatedFindAllContext(ctx context.Context, req Request) (uint, Responder, error) {
+	s.values = append(s.values, ctx.Value(contextKey("user")))
+	return s.PaginatedFindAll(req)
+}
+
+func (s *contextSource) CreateContext(ctx context.Context, obj interface{}, req Request) (Responder, error) {
+	s.values = append(s.values, ctx.Value(contextKey("user")))
+	return s.Create(obj, req)
+}
+
+func (s *contextSource) UpdateContext(ctx context.Context, obj interface{}, req Request) (Responder, error) {
+	s.values = append(s.values, ctx.Value(contextKey("user")))
+	return s.Update(obj, req)
+}
+
+func (s *contextSource) DeleteContext(ctx context.Context, id string, req Request) (Responder, error) {
+	s.values = append(s.values, ctx.Value(contextKey("user")))
+	return s.Delete(id, req)
+}
+
+var _ = Describe("Context aware resources", func() {
+	var (
+		api     *API
+		rec     *httptest.ResponseRecorder
+		source  *contextSource
+		handler http.Handler
+	)
+
+	BeforeEach(func() {
+		source = &contextSource{fixtureSource: fixtureSource{posts: map[string]*Post{
+			"1": {ID: "1", Title: "Hello, World!"},
+		}}}
+		api = NewAPI("v1")
+		api.AddResource(Post{}, source)
+		rec = httptest.NewRecorder()
+
+		// a middleware that attaches a value to the context of every request
+		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), contextKey("user"), "marvin")
+			api.Handler().ServeHTTP(w, r.WithContext(ctx))
+		})
+	})
+
+	It("uses the context of the plain request", func() {
+		r, err := http.NewRequest("GET", "/v1/posts", nil)
+		Expect(err).ToNot(HaveOccurred())
+		ctx := context.WithValue(r.Context(), contextKey("user"), "marvin")
+		req := buildRequest(r.WithContext(ctx))
+		Expect(req.Context().Value(contextKey("user"))).To(Equal("marvin"))
+	})
+
+	It("can replace the context of a request", func() {
+		req := Request{}
+		Expect(req.Context()).To(Equal(context.Background()))
+		ctx := context.WithValue(context.Background(), contextKey("user"), "marvin")
+		Expect(req.WithContext(ctx).Context()).To(Equal(ctx))
+		Expect(req.Context()).To(Equal(context.Background()))
+	})
+
+	It("prefers the context aware methods for all routes", func() {
+		requests := []struct {
+			method, url, body string
+		}{
+			{"GET", "/v1/posts", ""},
+			{"GET", "/v1/posts?page[offset]=0&page[limit]=1", ""},
+			{"GET", "/v1/posts/1", ""},
+			{"POST", "/v1/posts", `{"data": {"type": "posts", "attributes": {"title": "New Post"}}}`},
+			{"PATCH", "/v1/posts/1", `{"data": {"type": "posts", "id": "1", "attributes": {"title": "New Title"}}}`},
+			{"DELETE", "/v1/posts/1", ""},
+		}
+
+		for _, request := range requests {
+			req, err := http.NewRequest(request.method, request.url, strings.NewReader(request.body))
+			Expect(err).ToNot(HaveOccurred())
+			handler.ServeHTTP(rec, req)
+		}
+
+		// PATCH calls FindOne before Update
+		Expect(source.values).To(HaveLen(7))
+		for _, value := range source.values {
+			Expect(value).To(Equal("marvin"))
+		}
+	})
+})
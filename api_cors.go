@@ -0,0 +1,96 @@
+package api2go
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSOptions configure the handling of cross-origin requests, see SetCORSOptions
+type CORSOptions struct {
+	// AllowedOrigins are the origins that can access the api, "*" allows all of them
+	AllowedOrigins []string
+	// AllowedHeaders are the request headers that can be used, all requested headers are allowed if empty
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers that scripts can read, e.g. Location or ETag
+	ExposedHeaders []string
+	// AllowCredentials allows requests with cookies or authorization headers
+	AllowCredentials bool
+	// MaxAge is how long the result of a preflight request can be cached, the default of the browser if zero
+	MaxAge time.Duration
+}
+
+// SetCORSOptions enables cross-origin requests. OPTIONS requests of allowed origins are answered as preflight
+// requests with the methods of the requested route, all other responses of resource routes get the
+// Access-Control-Allow-Origin header.
+func (api *API) SetCORSOptions(options CORSOptions) {
+	api.cors = &options
+}
+
+// allowedOrigin returns the value of the Access-Control-Allow-Origin header for the origin of r,
+// or an empty string if it is not allowed
+func (api *API) allowedOrigin(r *http.Request) string {
+	origin := r.Header.Get("Origin")
+	if api.cors == nil || origin == "" {
+		return ""
+	}
+
+	for _, allowed := range api.cors.AllowedOrigins {
+		if allowed == "*" && !api.cors.AllowCredentials {
+			return "*"
+		}
+
+		// credentials can not be used with a wildcard, so the origin is mirrored
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return origin
+		}
+	}
+
+	return ""
+}
+
+func (api *API) setCORSHeaders(w http.ResponseWriter, r *http.Request) {
+	if api.cors == nil {
+		return
+	}
+
+	w.Header().Add("Vary", "Origin")
+
+	origin := api.allowedOrigin(r)
+	if origin == "" {
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	if api.cors.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+	if len(api.cors.ExposedHeaders) > 0 {
+		w.Header().Set("Access-Control-Expose-Headers", strings.Join(api.cors.ExposedHeaders, ","))
+	}
+}
+
+// handlePreflight adds the headers of a CORS preflight response if r is one from an allowed origin
+func (api *API) handlePreflight(w http.ResponseWriter, r *http.Request, allowedMethods string) {
+	if r.Header.Get("Access-Control-Request-Method") == "" {
+		return
+	}
+
+	api.setCORSHeaders(w, r)
+	if w.Header().Get("Access-Control-Allow-Origin") == "" {
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Methods", allowedMethods)
+
+	if len(api.cors.AllowedHeaders) > 0 {
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(api.cors.AllowedHeaders, ","))
+	} else if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+		w.Header().Set("Access-Control-Allow-Headers", requested)
+	}
+
+	if api.cors.MaxAge > 0 {
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(api.cors.MaxAge/time.Second)))
+	}
+}
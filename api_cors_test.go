@@ -0,0 +1,109 @@
+package api2go
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Allowed methods and CORS", func() {
+	var (
+		api *API
+		rec *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		api = NewAPI("v1")
+		api.AddResource(Post{}, &fixtureSource{posts: map[string]*Post{"1": {ID: "1", Title: "Hello, World!"}}})
+		rec = httptest.NewRecorder()
+	})
+
+	doRequest := func(method, url string, header http.Header) {
+		req, err := http.NewRequest(method, url, nil)
+		Expect(err).ToNot(HaveOccurred())
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		api.Handler().ServeHTTP(rec, req)
+	}
+
+	It("lists the methods of to-many relationship routes", func() {
+		doRequest("OPTIONS", "/v1/posts/1/relationships/comments", nil)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(rec.Header().Get("Allow")).To(Equal("GET,POST,PATCH,DELETE,OPTIONS"))
+	})
+
+	It("lists the methods of to-one relationship routes", func() {
+		doRequest("OPTIONS", "/v1/posts/1/relationships/author", nil)
+		Expect(rec.Header().Get("Allow")).To(Equal("GET,PATCH,OPTIONS"))
+	})
+
+	It("lists the methods of related resource routes", func() {
+		doRequest("OPTIONS", "/v1/posts/1/comments", nil)
+		Expect(rec.Header().Get("Allow")).To(Equal("GET,OPTIONS"))
+	})
+
+	It("sets the Allow header of 405 responses", func() {
+		doRequest("POST", "/v1/posts/1", nil)
+		Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+		Expect(rec.Header().Get("Allow")).To(Equal("GET,PATCH,DELETE,OPTIONS"))
+	})
+
+	It("does not handle preflight requests without CORS options", func() {
+		doRequest("OPTIONS", "/v1/posts", http.Header{
+			"Origin":                        {"https://example.com"},
+			"Access-Control-Request-Method": {"POST"},
+		})
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(rec.Header()).ToNot(HaveKey("Access-Control-Allow-Origin"))
+	})
+
+	Context("with CORS options", func() {
+		BeforeEach(func() {
+			api.SetCORSOptions(CORSOptions{
+				AllowedOrigins: []string{"https://example.com"},
+				ExposedHeaders: []string{"Location"},
+				MaxAge:         time.Hour,
+			})
+		})
+
+		It("answers preflight requests of allowed origins", func() {
+			doRequest("OPTIONS", "/v1/posts/1", http.Header{
+				"Origin":                         {"https://example.com"},
+				"Access-Control-Request-Method":  {"PATCH"},
+				"Access-Control-Request-Headers": {"Content-Type"},
+			})
+			Expect(rec.Code).To(Equal(http.StatusNoContent))
+			Expect(rec.Header().Get("Access-Control-Allow-Origin")).To(Equal("https://example.com"))
+			Expect(rec.Header().Get("Access-Control-Allow-Methods")).To(Equal("GET,PATCH,DELETE,OPTIONS"))
+			Expect(rec.Header().Get("Access-Control-Allow-Headers")).To(Equal("Content-Type"))
+			Expect(rec.Header().Get("Access-Control-Max-Age")).To(Equal("3600"))
+			Expect(rec.Header().Get("Vary")).To(Equal("Origin"))
+		})
+
+		It("rejects other origins", func() {
+			doRequest("OPTIONS", "/v1/posts/1", http.Header{
+				"Origin":                        {"https://evil.example.com"},
+				"Access-Control-Request-Method": {"DELETE"},
+			})
+			Expect(rec.Header()).ToNot(HaveKey("Access-Control-Allow-Origin"))
+			Expect(rec.Header()).ToNot(HaveKey("Access-Control-Allow-Methods"))
+		})
+
+		It("adds the CORS headers to responses", func() {
+			doRequest("GET", "/v1/posts/1", http.Header{"Origin": {"https://example.com"}})
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Header().Get("Access-Control-Allow-Origin")).To(Equal("https://example.com"))
+			Expect(rec.Header().Get("Access-Control-Expose-Headers")).To(Equal("Location"))
+		})
+
+		It("allows all origins with a wildcard", func() {
+			api.SetCORSOptions(CORSOptions{AllowedOrigins: []string{"*"}})
+			doRequest("GET", "/v1/posts/1", http.Header{"Origin": {"https://example.com"}})
+			Expect(rec.Header().Get("Access-Control-Allow-Origin")).To(Equal("*"))
+		})
+	})
+})
@@ -0,0 +1,186 @@
+package api2go
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// cursorSource pages through the posts 1 to 5 and uses their ids as cursors
+type cursorSource struct{}
+
+func (s *cursorSource) FindOne(ID string, req Request) (Responder, error) {
+	return &Response{Res: Post{ID: ID, Title: "Post " + ID}}, nil
+}
+
+func (s *cursorSource) Create(obj interface{}, req Request) (Responder, error) {
+	return &Response{Res: obj, Code: http.StatusCreated}, nil
+}
+
+func (s *cursorSource) Delete(id string, req Request) (Responder, error) {
+	return &Response{Code: http.StatusNoContent}, nil
+}
+
+func (s *cursorSource) Update(obj interface{}, req Request) (Responder, error) {
+	return &Response{Code: http.StatusNoContent}, nil
+}
+
+func (s *cursorSource) CursorPaginatedFindAll(req Request) (Cursors, Responder, error) {
+	size := 2
+	if values, ok := req.QueryParams["page[size]"]; ok {
+		size, _ = strconv.Atoi(values[0])
+	}
+
+	start, end := 1, 1+size
+	if values, ok := req.QueryParams["page[after]"]; ok {
+		after, _ := strconv.Atoi(values[0])
+		start, end = after+1, after+1+size
+	} else if values, ok := req.QueryParams["page[before]"]; ok {
+		before, _ := strconv.Atoi(values[0])
+		start, end = before-size, before
+	}
+	if start < 1 {
+		start = 1
+	}
+	if end > 6 {
+		end = 6
+	}
+
+	posts := []Post{}
+	for id := start; id < end; id++ {
+		posts = append(posts, Post{ID: strconv.Itoa(id), Title: "Post " + strconv.Itoa(id)})
+	}
+
+	cursors := Cursors{}
+	if start > 1 {
+		cursors.Prev = strconv.Itoa(start)
+	}
+	if end < 6 {
+		cursors.Next = strconv.Itoa(end - 1)
+	}
+
+	return cursors, &Response{Res: posts}, nil
+}
+
+// base64CursorSource pages like cursorSource, but uses base64 encoded cursors with reserved characters
+type base64CursorSource struct {
+	cursorSource
+}
+
+func (s *base64CursorSource) CursorPaginatedFindAll(req Request) (Cursors, Responder, error) {
+	for _, parameter := range []string{"page[after]", "page[before]"} {
+		if values, ok := req.QueryParams[parameter]; ok {
+			cursor, err := base64.StdEncoding.DecodeString(values[0])
+			if err != nil {
+				return Cursors{}, nil, err
+			}
+			req.QueryParams[parameter] = []string{strconv.Itoa(int(cursor[len(cursor)-1]))}
+		}
+	}
+
+	cursors, response, err := s.cursorSource.CursorPaginatedFindAll(req)
+	encode := func(cursor string) string {
+		if cursor == "" {
+			return ""
+		}
+		id, _ := strconv.Atoi(cursor)
+		return base64.StdEncoding.EncodeToString([]byte{0xfb, 0xff, 0xbf, byte(id)})
+	}
+
+	return Cursors{Prev: encode(cursors.Prev), Next: encode(cursors.Next)}, response, err
+}
+
+var _ = Describe("Cursor pagination", func() {
+	var (
+		api *API
+		rec *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		api = NewAPIWithBaseURL("v1", "http://localhost")
+		api.AddResource(Post{}, &cursorSource{})
+		rec = httptest.NewRecorder()
+	})
+
+	doRequest := func(url string) map[string]interface{} {
+		req, err := http.NewRequest("GET", url, nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+
+		var result map[string]interface{}
+		Expect(json.Unmarshal(rec.Body.Bytes(), &result)).To(Succeed())
+		return result
+	}
+
+	It("returns the first page with a next link", func() {
+		result := doRequest("/v1/posts?page[size]=2")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(result["data"]).To(HaveLen(2))
+		Expect(result["links"]).To(Equal(map[string]interface{}{
+			"next": "http://localhost/v1/posts?page[after]=2&page[size]=2",
+		}))
+	})
+
+	It("returns pages after a cursor with prev and next links", func() {
+		result := doRequest("/v1/posts?page[after]=2&page[size]=2")
+		Expect(result["data"]).To(HaveLen(2))
+		Expect(result["links"]).To(Equal(map[string]interface{}{
+			"prev": "http://localhost/v1/posts?page[before]=3&page[size]=2",
+			"next": "http://localhost/v1/posts?page[after]=4&page[size]=2",
+		}))
+	})
+
+	It("returns the last page without a next link", func() {
+		result := doRequest("/v1/posts?page[after]=4&page[size]=2")
+		Expect(result["data"]).To(HaveLen(1))
+		Expect(result["links"]).To(Equal(map[string]interface{}{
+			"prev": "http://localhost/v1/posts?page[before]=5&page[size]=2",
+		}))
+	})
+
+	It("returns pages before a cursor", func() {
+		result := doRequest("/v1/posts?page[before]=5&page[size]=2")
+		Expect(result["data"]).To(HaveLen(2))
+		Expect(result["links"]).To(HaveKeyWithValue("next", "http://localhost/v1/posts?page[after]=4&page[size]=2"))
+	})
+
+	It("keeps base64 cursors escaped in links", func() {
+		api = NewAPIWithBaseURL("v1", "http://localhost")
+		api.AddResource(Post{}, &base64CursorSource{})
+
+		result := doRequest("/v1/posts?page[size]=2")
+		next := result["links"].(map[string]interface{})["next"].(string)
+		Expect(next).To(Equal("http://localhost/v1/posts?page[after]=%2B%2F%2B%2FAg%3D%3D&page[size]=2"))
+
+		rec = httptest.NewRecorder()
+		result = doRequest(strings.TrimPrefix(next, "http://localhost"))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(result["data"]).To(HaveLen(2))
+		Expect(result["data"].([]interface{})[0]).To(HaveKeyWithValue("id", "3"))
+		prev := result["links"].(map[string]interface{})["prev"].(string)
+		Expect(prev).To(Equal("http://localhost/v1/posts?page[before]=%2B%2F%2B%2FAw%3D%3D&page[size]=2"))
+
+		rec = httptest.NewRecorder()
+		result = doRequest(strings.TrimPrefix(prev, "http://localhost"))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(result["data"].([]interface{})[0]).To(HaveKeyWithValue("id", "1"))
+	})
+
+	It("uses cursor pagination for sources without FindAll", func() {
+		result := doRequest("/v1/posts")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(result["data"]).To(HaveLen(2))
+	})
+
+	It("rejects invalid page sizes", func() {
+		doRequest("/v1/posts?page[size]=0")
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(rec.Body.String()).To(ContainSubstring(`"parameter":"page[size]"`))
+	})
+})
@@ -0,0 +1,148 @@
+package api2go
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/manyminds/api2go/jsonapi"
+)
+
+// checkResourceObject returns 409 Conflict if the resource object at pointer of a request document
+// does not belong to the resource, or does not have the given id if id is not empty. The resource
+// linkage of its relationships must have the types of the relations.
+func (res *resource) checkResourceObject(data interface{}, pointer, id string) error {
+	object, ok := data.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	if objectType, ok := object["type"].(string); ok && objectType != res.name {
+		return newPointerError(http.StatusConflict, "Type mismatch",
+			fmt.Sprintf("type %s does not match the type %s of the endpoint", objectType, res.name), pointer+"/type")
+	}
+
+	if objectID, ok := object["id"].(string); ok && id != "" && objectID != id {
+		return newPointerError(http.StatusConflict, "ID mismatch",
+			fmt.Sprintf("id %s does not match the id %s of the endpoint", objectID, id), pointer+"/id")
+	}
+
+	relationships, _ := object["relationships"].(map[string]interface{})
+	for name, relationship := range relationships {
+		relation, found := res.reference(name)
+		if !found {
+			continue
+		}
+
+		if relationship, ok := relationship.(map[string]interface{}); ok {
+			if err := checkLinkage(relation, relationship["data"], pointer+"/relationships/"+name+"/data"); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// singleResourceObject returns the resource object of a request that creates one resource and its pointer.
+// Like unmarshalling, it accepts a resource object wrapped in an array with one element, other arrays
+// are answered with 400 Bad Request.
+func singleResourceObject(data interface{}) (interface{}, string, error) {
+	elements, ok := data.([]interface{})
+	if !ok {
+		return data, "/data", nil
+	}
+
+	if len(elements) != 1 {
+		return nil, "", newPointerError(http.StatusBadRequest, "Invalid document", "data must contain one resource object", "/data")
+	}
+
+	return elements[0], "/data/0", nil
+}
+
+// checkLinkage returns 400 Bad Request if the resource linkage at pointer does not fit the cardinality of
+// relation, and 409 Conflict if one of its resource identifiers does not have the type of relation
+func checkLinkage(relation jsonapi.Reference, data interface{}, pointer string) error {
+	var identifiers []interface{}
+	switch data := data.(type) {
+	case nil:
+		if isToMany(relation) {
+			return newPointerError(http.StatusBadRequest, "Invalid resource linkage",
+				fmt.Sprintf("data of to-many relationship %s must be an array", relation.Name), pointer)
+		}
+	case map[string]interface{}:
+		if isToMany(relation) {
+			return newPointerError(http.StatusBadRequest, "Invalid resource linkage",
+				fmt.Sprintf("data of to-many relationship %s must be an array", relation.Name), pointer)
+		}
+
+		return checkIdentifier(relation, data, pointer)
+	case []interface{}:
+		if !isToMany(relation) {
+			return newPointerError(http.StatusBadRequest, "Invalid resource linkage",
+				fmt.Sprintf("data of to-one relationship %s must be an object or null", relation.Name), pointer)
+		}
+
+		identifiers = data
+	default:
+		return newPointerError(http.StatusBadRequest, "Invalid resource linkage",
+			fmt.Sprintf("data of relationship %s must be resource linkage", relation.Name), pointer)
+	}
+
+	for i, identifier := range identifiers {
+		if err := checkIdentifier(relation, identifier, fmt.Sprintf("%s/%d", pointer, i)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkIdentifier checks a resource identifier object of the resource linkage of relation
+func checkIdentifier(relation jsonapi.Reference, identifier interface{}, pointer string) error {
+	object, ok := identifier.(map[string]interface{})
+	if !ok {
+		return newPointerError(http.StatusBadRequest, "Invalid resource identifier", "resource identifiers must be objects", pointer)
+	}
+
+	if _, ok := object["id"].(string); !ok {
+		return newPointerError(http.StatusBadRequest, "Invalid resource identifier", "resource identifiers must have a string id", pointer+"/id")
+	}
+
+	identifierType, ok := object["type"].(string)
+	if !ok {
+		return newPointerError(http.StatusBadRequest, "Invalid resource identifier", "resource identifiers must have a string type", pointer+"/type")
+	}
+
+	if relation.Type != "" && identifierType != relation.Type {
+		return newPointerError(http.StatusConflict, "Type mismatch",
+			fmt.Sprintf("type %s does not match the type %s of relationship %s", identifierType, relation.Type, relation.Name), pointer+"/type")
+	}
+
+	return nil
+}
+
+// newMissingLinkageError returns 400 Bad Request for relationship requests without data
+func newMissingLinkageError() HTTPError {
+	return newPointerError(http.StatusBadRequest, "Missing resource linkage", "relationship requests must contain data", "/data")
+}
+
+// checkDocument returns 400 Bad Request with an Error for every violation of the structure rules
+// of JSON:API in a request document
+func checkDocument(violations []jsonapi.DocumentError) error {
+	if len(violations) == 0 {
+		return nil
+	}
+
+	httpErr := NewHTTPError(nil, "Invalid document", http.StatusBadRequest)
+	for _, violation := range violations {
+		httpErr.Errors = append(httpErr.Errors, Error{
+			Status: strconv.Itoa(http.StatusBadRequest),
+			Title:  "Invalid document",
+			Detail: violation.Detail,
+			Source: &ErrorSource{Pointer: violation.Pointer},
+		})
+	}
+
+	return httpErr
+}
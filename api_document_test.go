@@ -0,0 +1,159 @@
+package api2go
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Request documents", func() {
+	var (
+		api    *API
+		rec    *httptest.ResponseRecorder
+		source *fixtureSource
+	)
+
+	BeforeEach(func() {
+		source = &fixtureSource{posts: map[string]*Post{"1": {ID: "1", Title: "Hello, World!"}}}
+		api = NewAPI("v1")
+		api.AddResource(Post{}, source)
+		rec = httptest.NewRecorder()
+	})
+
+	doRequest := func(method, url, body string) {
+		req, err := http.NewRequest(method, url, strings.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+	}
+
+	Context("create", func() {
+		It("responds with 400 Bad Request to malformed documents", func() {
+			doRequest("POST", "/v1/posts", `{"data": {"type": "posts", "attributes": "New", "relationships": {"author": {}}}}`)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.Bytes()).To(MatchJSON(`{"errors": [
+				{"status": "400", "title": "Invalid document", "detail": "attributes must be an object", "source": {"pointer": "/data/attributes"}},
+				{"status": "400", "title": "Invalid document", "detail": "relationships of request documents must contain data", "source": {"pointer": "/data/relationships/author/data"}}
+			]}`))
+			Expect(source.posts).To(HaveLen(1))
+		})
+
+		It("responds with 409 Conflict to another type", func() {
+			doRequest("POST", "/v1/posts", `{"data": {"type": "comments", "attributes": {"title": "New"}}}`)
+			Expect(rec.Code).To(Equal(http.StatusConflict))
+			Expect(rec.Body.Bytes()).To(MatchJSON(`{"errors": [{
+				"status": "409",
+				"title": "Type mismatch",
+				"detail": "type comments does not match the type posts of the endpoint",
+				"source": {"pointer": "/data/type"}
+			}]}`))
+			Expect(source.posts).To(HaveLen(1))
+		})
+
+		It("responds with 409 Conflict to another type in an array with one element", func() {
+			doRequest("POST", "/v1/posts", `{"data": [{"type": "comments", "attributes": {"title": "New"}}]}`)
+			Expect(rec.Code).To(Equal(http.StatusConflict))
+			Expect(rec.Body.Bytes()).To(MatchJSON(`{"errors": [{
+				"status": "409",
+				"title": "Type mismatch",
+				"detail": "type comments does not match the type posts of the endpoint",
+				"source": {"pointer": "/data/0/type"}
+			}]}`))
+			Expect(source.posts).To(HaveLen(1))
+		})
+
+		It("responds with 409 Conflict to relationships with another type", func() {
+			doRequest("POST", "/v1/posts", `{"data": {"type": "posts", "attributes": {"title": "New"}, "relationships": {
+				"comments": {"data": [{"type": "comments", "id": "1"}, {"type": "users", "id": "1"}]}
+			}}}`)
+			Expect(rec.Code).To(Equal(http.StatusConflict))
+			Expect(rec.Body.String()).To(ContainSubstring(`"pointer":"/data/relationships/comments/data/1/type"`))
+		})
+	})
+
+	Context("update", func() {
+		It("responds with 409 Conflict to another id", func() {
+			doRequest("PATCH", "/v1/posts/1", `{"data": {"type": "posts", "id": "2", "attributes": {"title": "New"}}}`)
+			Expect(rec.Code).To(Equal(http.StatusConflict))
+			Expect(rec.Body.Bytes()).To(MatchJSON(`{"errors": [{
+				"status": "409",
+				"title": "ID mismatch",
+				"detail": "id 2 does not match the id 1 of the endpoint",
+				"source": {"pointer": "/data/id"}
+			}]}`))
+			Expect(source.posts["1"].Title).To(Equal("Hello, World!"))
+		})
+
+		It("responds with 400 Bad Request to an array of resource objects", func() {
+			doRequest("PATCH", "/v1/posts/1", `{"data": [{"type": "posts", "id": "1", "attributes": {"title": "New"}}]}`)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.Bytes()).To(MatchJSON(`{"errors": [{
+				"status": "400",
+				"title": "Invalid document",
+				"detail": "data of update requests must be a resource object",
+				"source": {"pointer": "/data"}
+			}]}`))
+		})
+
+		It("responds with 409 Conflict to another type", func() {
+			doRequest("PATCH", "/v1/posts/1", `{"data": {"type": "users", "id": "1", "attributes": {"title": "New"}}}`)
+			Expect(rec.Code).To(Equal(http.StatusConflict))
+			Expect(rec.Body.String()).To(ContainSubstring(`"pointer":"/data/type"`))
+			Expect(source.posts["1"].Title).To(Equal("Hello, World!"))
+		})
+
+		It("responds with 409 Conflict to a to-one relationship with another type", func() {
+			doRequest("PATCH", "/v1/posts/1", `{"data": {"type": "posts", "id": "1", "relationships": {
+				"author": {"data": {"type": "comments", "id": "1"}}
+			}}}`)
+			Expect(rec.Code).To(Equal(http.StatusConflict))
+			Expect(rec.Body.String()).To(ContainSubstring(`"pointer":"/data/relationships/author/data/type"`))
+			Expect(source.posts["1"].Author).To(BeNil())
+		})
+	})
+
+	Context("relationships", func() {
+		It("responds with 409 Conflict to a to-one relationship with another type", func() {
+			doRequest("PATCH", "/v1/posts/1/relationships/author", `{"data": {"type": "comments", "id": "1"}}`)
+			Expect(rec.Code).To(Equal(http.StatusConflict))
+			Expect(rec.Body.Bytes()).To(MatchJSON(`{"errors": [{
+				"status": "409",
+				"title": "Type mismatch",
+				"detail": "type comments does not match the type users of relationship author",
+				"source": {"pointer": "/data/type"}
+			}]}`))
+		})
+
+		It("responds with 409 Conflict to a to-many relationship with another type", func() {
+			doRequest("POST", "/v1/posts/1/relationships/comments", `{"data": [{"type": "comments", "id": "1"}, {"type": "users", "id": "1"}]}`)
+			Expect(rec.Code).To(Equal(http.StatusConflict))
+			Expect(rec.Body.String()).To(ContainSubstring(`"pointer":"/data/1/type"`))
+			Expect(source.posts["1"].Comments).To(BeEmpty())
+		})
+
+		It("responds with 400 Bad Request to an object for a to-many relationship", func() {
+			doRequest("PATCH", "/v1/posts/1/relationships/comments", `{"data": {"type": "comments", "id": "1"}}`)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(ContainSubstring(`"pointer":"/data"`))
+		})
+
+		It("responds with 400 Bad Request to resource identifiers without id", func() {
+			doRequest("DELETE", "/v1/posts/1/relationships/comments", `{"data": [{"type": "comments"}]}`)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(ContainSubstring(`"pointer":"/data/0/id"`))
+		})
+
+		It("responds with 400 Bad Request to documents without data", func() {
+			doRequest("PATCH", "/v1/posts/1/relationships/author", `{}`)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.Bytes()).To(MatchJSON(`{"errors": [{
+				"status": "400",
+				"title": "Invalid document",
+				"detail": "request documents must contain data",
+				"source": {"pointer": "/data"}
+			}]}`))
+		})
+	})
+})
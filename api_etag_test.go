@@ -0,0 +1,174 @@
+package api2go
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type Revision struct {
+	ID     string `json:"-"`
+	Text   string
+	Number int
+}
+
+func (r Revision) GetID() string {
+	return r.ID
+}
+
+func (r *Revision) SetID(ID string) error {
+	r.ID = ID
+	return nil
+}
+
+func (r Revision) Version() string {
+	return strconv.Itoa(r.Number)
+}
+
+type revisionSource struct {
+	revision Revision
+}
+
+func (s *revisionSource) FindOne(ID string, req Request) (Responder, error) {
+	return &Response{Res: s.revision}, nil
+}
+
+func (s *revisionSource) Create(obj interface{}, req Request) (Responder, error) {
+	return &Response{Code: http.StatusNoContent}, nil
+}
+
+func (s *revisionSource) Delete(id string, req Request) (Responder, error) {
+	return &Response{Code: http.StatusNoContent}, nil
+}
+
+func (s *revisionSource) Update(obj interface{}, req Request) (Responder, error) {
+	s.revision = obj.(Revision)
+	s.revision.Number++
+	return &Response{Res: s.revision, Code: http.StatusOK}, nil
+}
+
+var _ = Describe("ETags", func() {
+	var (
+		api *API
+		rec *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		api = NewAPI("v1")
+		api.AddResource(Post{}, &fixtureSource{map[string]*Post{
+			"1": {ID: "1", Title: "Hello, World!"},
+		}, false}, WithETags())
+		api.AddResource(Revision{}, &revisionSource{Revision{ID: "1", Text: "first", Number: 1}}, WithETags())
+		api.AddResource(Comment{}, &commentSource{})
+		rec = httptest.NewRecorder()
+	})
+
+	doRequest := func(method, url, body string, header http.Header) {
+		req, err := http.NewRequest(method, url, strings.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		rec = httptest.NewRecorder()
+		api.Handler().ServeHTTP(rec, req)
+	}
+
+	It("sets a hash as ETag", func() {
+		doRequest("GET", "/v1/posts/1", "", nil)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("ETag")).To(MatchRegexp(`^"[0-9a-f]{40}"$`))
+
+		etag := rec.Header().Get("ETag")
+		doRequest("GET", "/v1/posts/1", "", http.Header{"If-None-Match": {etag}})
+		Expect(rec.Code).To(Equal(http.StatusNotModified))
+		Expect(rec.Body.Len()).To(Equal(0))
+	})
+
+	It("sets another hash for other representations", func() {
+		doRequest("GET", "/v1/posts/1", "", nil)
+		etag := rec.Header().Get("ETag")
+
+		doRequest("GET", "/v1/posts/1?fields[posts]=title", "", http.Header{"If-None-Match": {etag}})
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("ETag")).To(MatchRegexp(`^"[0-9a-f]{40}"$`))
+		Expect(rec.Header().Get("ETag")).ToNot(Equal(etag))
+	})
+
+	It("updates if If-Match matches the hash of a read", func() {
+		doRequest("GET", "/v1/posts/1", "", nil)
+		etag := rec.Header().Get("ETag")
+
+		doRequest("PATCH", "/v1/posts/1", `{"data": {"type": "posts", "id": "1", "attributes": {"title": "New"}}}`,
+			http.Header{"If-Match": {etag}})
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+
+		doRequest("PATCH", "/v1/posts/1", `{"data": {"type": "posts", "id": "1", "attributes": {"title": "Newer"}}}`,
+			http.Header{"If-Match": {etag}})
+		Expect(rec.Code).To(Equal(http.StatusPreconditionFailed))
+	})
+
+	It("sets a hash of collections as ETag", func() {
+		doRequest("GET", "/v1/posts", "", nil)
+		etag := rec.Header().Get("ETag")
+		Expect(etag).ToNot(BeEmpty())
+
+		doRequest("GET", "/v1/posts", "", http.Header{"If-None-Match": {`"other", W/` + etag}})
+		Expect(rec.Code).To(Equal(http.StatusNotModified))
+	})
+
+	It("uses the version of Versioner models", func() {
+		doRequest("GET", "/v1/revisions/1", "", nil)
+		Expect(rec.Header().Get("ETag")).To(Equal(`"1"`))
+
+		doRequest("GET", "/v1/revisions/1", "", http.Header{"If-None-Match": {`"0"`}})
+		Expect(rec.Code).To(Equal(http.StatusOK))
+	})
+
+	It("combines the version with the include and fields parameters", func() {
+		doRequest("GET", "/v1/revisions/1?fields[revisions]=text", "", nil)
+		etag := rec.Header().Get("ETag")
+		Expect(etag).To(MatchRegexp(`^"1-[0-9a-f]{40}"$`))
+
+		doRequest("GET", "/v1/revisions/1", "", http.Header{"If-None-Match": {etag}})
+		Expect(rec.Code).To(Equal(http.StatusOK))
+
+		doRequest("GET", "/v1/revisions/1?fields[revisions]=text", "", http.Header{"If-None-Match": {etag}})
+		Expect(rec.Code).To(Equal(http.StatusNotModified))
+	})
+
+	It("updates if If-Match matches and sets the new ETag", func() {
+		doRequest("PATCH", "/v1/revisions/1", `{"data": {"type": "revisions", "id": "1", "attributes": {"text": "second"}}}`,
+			http.Header{"If-Match": {`"1"`}})
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("ETag")).To(Equal(`"2"`))
+	})
+
+	It("rejects updates with an outdated If-Match", func() {
+		doRequest("PATCH", "/v1/revisions/1", `{"data": {"type": "revisions", "id": "1", "attributes": {"text": "second"}}}`,
+			http.Header{"If-Match": {`"0"`}})
+		Expect(rec.Code).To(Equal(http.StatusPreconditionFailed))
+		Expect(rec.Body.Bytes()).To(MatchJSON(`{"errors": [{
+			"status": "412",
+			"title": "Precondition Failed",
+			"detail": "revisions has been modified, the current entity tag is \"1\"",
+			"source": {"parameter": "If-Match"}
+		}]}`))
+	})
+
+	It("rejects deletes with an outdated If-Match", func() {
+		doRequest("DELETE", "/v1/revisions/1", "", http.Header{"If-Match": {`W/"1"`}})
+		Expect(rec.Code).To(Equal(http.StatusPreconditionFailed))
+
+		doRequest("DELETE", "/v1/revisions/1", "", http.Header{"If-Match": {`*`}})
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+	})
+
+	It("does not set ETags without WithETags", func() {
+		doRequest("GET", "/v1/comments/1", "", nil)
+		Expect(rec.Header().Get("ETag")).To(BeEmpty())
+	})
+})
@@ -0,0 +1,269 @@
+package api2go
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Extension is a JSON:API extension (https://jsonapi.org/format/#extensions). All members it adds to
+// documents are named with its namespace, e.g. "version:id" for the namespace "version".
+type Extension interface {
+	// URI identifies the extension in the ext parameter of the media type
+	URI() string
+	// Namespace prefixes the names of all members of the extension
+	Namespace() string
+	// ValidateMember checks a member of the extension in a request document, name is without the namespace.
+	// The returned error is sent with 400 Bad Request and a pointer to the member.
+	ValidateMember(name string, value interface{}) error
+	// Decorate adds the members of the extension to a response document
+	Decorate(document map[string]interface{}, r *http.Request)
+}
+
+// Profile is a JSON:API profile (https://jsonapi.org/format/#profiles), e.g. one that adds timestamps to the
+// meta of resources
+type Profile interface {
+	// URI identifies the profile in the profile parameter of the media type and in jsonapi.profile
+	URI() string
+	// Decorate adds the members of the profile to a response document
+	Decorate(document map[string]interface{}, r *http.Request)
+}
+
+// RegisterExtension adds an extension to the api. Members of request documents whose namespace is not
+// registered are rejected with 400 Bad Request, with SetStrictMediaTypes also the ones of extensions that
+// are not listed in the ext parameter of the Content-Type. If a request lists the extension, it is applied
+// to the response documents. The ext parameter of the Content-Type of responses lists all extensions
+// that are applied or whose members the document contains.
+func (api *API) RegisterExtension(extension Extension) {
+	api.mediaTypes.extensions = append(api.mediaTypes.extensions, extension)
+}
+
+// RegisterProfile adds a profile to the api, which is applied to all response documents and listed in
+// jsonapi.profile and in the profile parameter of their Content-Type
+func (api *API) RegisterProfile(profile Profile) {
+	api.mediaTypes.profiles = append(api.mediaTypes.profiles, profile)
+}
+
+// mediaTypes contains the extensions and profiles of an api and if it enforces the media type rules
+type mediaTypes struct {
+	strict     bool
+	extensions []Extension
+	profiles   []Profile
+}
+
+type mediaTypesKey struct{}
+
+// withMediaTypes returns r with a context that carries m, so that request and response documents can
+// be processed without access to the api
+func (m *mediaTypes) withMediaTypes(r *http.Request) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), mediaTypesKey{}, m))
+}
+
+// mediaTypesOf returns the media types of the api that handles r, which are empty outside of api routes
+func mediaTypesOf(r *http.Request) *mediaTypes {
+	if m, ok := r.Context().Value(mediaTypesKey{}).(*mediaTypes); ok {
+		return m
+	}
+
+	return &mediaTypes{}
+}
+
+func (m *mediaTypes) supports(uri string) bool {
+	for _, extension := range m.extensions {
+		if extension.URI() == uri {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *mediaTypes) extension(namespace string) Extension {
+	for _, extension := range m.extensions {
+		if extension.Namespace() == namespace {
+			return extension
+		}
+	}
+
+	return nil
+}
+
+// applied returns the registered extensions the request lists in its ext parameter
+func (m *mediaTypes) applied(r *http.Request) []Extension {
+	requested, _ := mediaTypeParameters(r)
+
+	var result []Extension
+	for _, extension := range m.extensions {
+		for _, uri := range requested {
+			if extension.URI() == uri {
+				result = append(result, extension)
+				break
+			}
+		}
+	}
+
+	return result
+}
+
+// checkMembers returns 400 Bad Request for every member of an extension in a request document that is not
+// registered, invalid or, if strict, not applied to the request
+func (m *mediaTypes) checkMembers(doc map[string]interface{}, r *http.Request) error {
+	applied := m.applied(r)
+
+	httpErr := NewHTTPError(nil, "Invalid extension members", http.StatusBadRequest)
+	fail := func(title, detail, pointer string) {
+		httpErr.Errors = append(httpErr.Errors, Error{
+			Status: strconv.Itoa(http.StatusBadRequest),
+			Title:  title,
+			Detail: detail,
+			Source: &ErrorSource{Pointer: pointer},
+		})
+	}
+
+	for _, member := range extensionMembers(doc) {
+		namespace := strings.SplitN(member.name, ":", 2)[0]
+		extension := m.extension(namespace)
+		switch {
+		case extension == nil:
+			fail("Unknown extension", fmt.Sprintf("no extension with namespace %s is supported", namespace), member.pointer)
+		case m.strict && !containsExtension(applied, extension):
+			fail("Extension not applied", fmt.Sprintf("members of extension %s require it in the ext media type parameter", extension.URI()), member.pointer)
+		default:
+			if err := extension.ValidateMember(strings.TrimPrefix(member.name, namespace+":"), member.value); err != nil {
+				fail("Invalid extension member", err.Error(), member.pointer)
+			}
+		}
+	}
+
+	if len(httpErr.Errors) > 0 {
+		return httpErr
+	}
+
+	return nil
+}
+
+// decorate applies the extensions of the request and all profiles to a response document and returns
+// the media type parameters that list them
+func (m *mediaTypes) decorate(doc map[string]interface{}, r *http.Request) string {
+	var params []string
+
+	var extensions []string
+	for _, extension := range m.applied(r) {
+		extension.Decorate(doc, r)
+		extensions = append(extensions, extension.URI())
+	}
+	// e.g. the results of atomic operations apply their extension without being requested
+	for _, member := range extensionMembers(doc) {
+		extension := m.extension(strings.SplitN(member.name, ":", 2)[0])
+		if extension != nil && !containsURI(extensions, extension.URI()) {
+			extensions = append(extensions, extension.URI())
+		}
+	}
+	if len(extensions) > 0 {
+		params = append(params, `ext="`+strings.Join(extensions, " ")+`"`)
+	}
+
+	var profiles []string
+	for _, profile := range m.profiles {
+		profile.Decorate(doc, r)
+		profiles = append(profiles, profile.URI())
+	}
+	if len(profiles) > 0 {
+		params = append(params, `profile="`+strings.Join(profiles, " ")+`"`)
+
+		jsonapiObject, _ := doc["jsonapi"].(map[string]interface{})
+		if jsonapiObject == nil {
+			jsonapiObject = map[string]interface{}{}
+		}
+		jsonapiObject["profile"] = profiles
+		doc["jsonapi"] = jsonapiObject
+	}
+
+	if len(params) == 0 {
+		return ""
+	}
+
+	return "; " + strings.Join(params, "; ")
+}
+
+func containsURI(uris []string, uri string) bool {
+	for _, u := range uris {
+		if u == uri {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsExtension(extensions []Extension, extension Extension) bool {
+	for _, e := range extensions {
+		if e.URI() == extension.URI() {
+			return true
+		}
+	}
+
+	return false
+}
+
+type extensionMember struct {
+	name    string
+	value   interface{}
+	pointer string
+}
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// extensionMembers returns all members with a namespace of the objects of a document that are
+// defined by JSON:API: the top level, resource objects, relationship objects and resource identifiers
+func extensionMembers(doc map[string]interface{}) []extensionMember {
+	var members []extensionMember
+	visit := func(object map[string]interface{}, pointer string) {
+		names := make([]string, 0, len(object))
+		for name := range object {
+			if strings.Contains(name, ":") {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			members = append(members, extensionMember{name: name, value: object[name], pointer: pointer + "/" + pointerEscaper.Replace(name)})
+		}
+	}
+
+	var resourceObject func(value interface{}, pointer string)
+	resourceObject = func(value interface{}, pointer string) {
+		switch value := value.(type) {
+		case []interface{}:
+			for i, element := range value {
+				resourceObject(element, fmt.Sprintf("%s/%d", pointer, i))
+			}
+		case map[string]interface{}:
+			visit(value, pointer)
+
+			relationships, _ := value["relationships"].(map[string]interface{})
+			names := make([]string, 0, len(relationships))
+			for name := range relationships {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			for _, name := range names {
+				if relationship, ok := relationships[name].(map[string]interface{}); ok {
+					relationshipPointer := pointer + "/relationships/" + pointerEscaper.Replace(name)
+					visit(relationship, relationshipPointer)
+					resourceObject(relationship["data"], relationshipPointer+"/data")
+				}
+			}
+		}
+	}
+
+	visit(doc, "")
+	resourceObject(doc["data"], "/data")
+
+	return members
+}
@@ -0,0 +1,155 @@
+package api2go
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// versionExtension accepts string members named id and adds the version of the api to responses
+type versionExtension struct{}
+
+func (versionExtension) URI() string       { return "https://example.com/ext/version" }
+func (versionExtension) Namespace() string { return "version" }
+
+func (versionExtension) ValidateMember(name string, value interface{}) error {
+	if name != "id" {
+		return errors.New("only version:id is supported")
+	}
+	if _, ok := value.(string); !ok {
+		return errors.New("version:id must be a string")
+	}
+
+	return nil
+}
+
+func (versionExtension) Decorate(document map[string]interface{}, r *http.Request) {
+	document["version:current"] = "2"
+}
+
+// timestampsProfile adds a timestamp to the meta of response documents
+type timestampsProfile struct{}
+
+func (timestampsProfile) URI() string { return "https://example.com/profile/timestamps" }
+
+func (timestampsProfile) Decorate(document map[string]interface{}, r *http.Request) {
+	meta, _ := document["meta"].(map[string]interface{})
+	if meta == nil {
+		meta = map[string]interface{}{}
+	}
+	meta["timestamp"] = "2020-01-01T00:00:00Z"
+	document["meta"] = meta
+}
+
+var _ = Describe("Extensions and profiles", func() {
+	const versionMediaType = `application/vnd.api+json; ext="https://example.com/ext/version"`
+
+	var (
+		api *API
+		rec *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		api = NewAPI("v1")
+		api.AddResource(Post{}, &fixtureSource{posts: map[string]*Post{"1": {ID: "1", Title: "Hello, World!"}}})
+		api.RegisterExtension(versionExtension{})
+		rec = httptest.NewRecorder()
+	})
+
+	doRequest := func(method, url, body string, header http.Header) {
+		req, err := http.NewRequest(method, url, strings.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+		req.Header = header
+		api.Handler().ServeHTTP(rec, req)
+	}
+
+	It("applies requested extensions to responses", func() {
+		doRequest("POST", "/v1/posts", `{"data": {"type": "posts", "version:id": "1", "attributes": {"title": "New"}}}`,
+			http.Header{"Content-Type": {versionMediaType}})
+		Expect(rec.Code).To(Equal(http.StatusCreated))
+		Expect(rec.Header().Get("Content-Type")).To(Equal(versionMediaType))
+		Expect(rec.Body.String()).To(ContainSubstring(`"version:current":"2"`))
+	})
+
+	It("does not apply extensions that are not requested", func() {
+		doRequest("GET", "/v1/posts/1", "", http.Header{})
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("Content-Type")).To(Equal(defaultContentTypHeader))
+		Expect(rec.Body.String()).ToNot(ContainSubstring("version:"))
+	})
+
+	It("rejects invalid members and members of unknown extensions", func() {
+		doRequest("POST", "/v1/posts", `{
+			"data": {"type": "posts", "version:id": 1, "relationships": {"author": {"data": {"type": "users", "id": "1", "version:name": "a"}}}},
+			"other:meta": {}
+		}`, http.Header{"Content-Type": {versionMediaType}})
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(rec.Body.Bytes()).To(MatchJSON(`{"errors": [
+			{
+				"status": "400",
+				"title": "Unknown extension",
+				"detail": "no extension with namespace other is supported",
+				"source": {"pointer": "/other:meta"}
+			},
+			{
+				"status": "400",
+				"title": "Invalid extension member",
+				"detail": "version:id must be a string",
+				"source": {"pointer": "/data/version:id"}
+			},
+			{
+				"status": "400",
+				"title": "Invalid extension member",
+				"detail": "only version:id is supported",
+				"source": {"pointer": "/data/relationships/author/data/version:name"}
+			}
+		]}`))
+	})
+
+	It("accepts members of extensions that are not applied by default", func() {
+		doRequest("POST", "/v1/posts", `{"data": {"type": "posts", "version:id": "1", "attributes": {"title": "New"}}}`, http.Header{})
+		Expect(rec.Code).To(Equal(http.StatusCreated))
+		Expect(rec.Body.String()).ToNot(ContainSubstring("version:"))
+	})
+
+	It("rejects members of extensions that are not applied with strict media types", func() {
+		api.SetStrictMediaTypes(true)
+		doRequest("POST", "/v1/posts", `{"data": {"type": "posts", "version:id": "1"}}`, http.Header{})
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(rec.Body.Bytes()).To(MatchJSON(`{"errors": [{
+			"status": "400",
+			"title": "Extension not applied",
+			"detail": "members of extension https://example.com/ext/version require it in the ext media type parameter",
+			"source": {"pointer": "/data/version:id"}
+		}]}`))
+	})
+
+	It("rejects unsupported extensions with strict media types", func() {
+		api.SetStrictMediaTypes(true)
+		doRequest("POST", "/v1/posts", `{"data": {"type": "posts"}}`, http.Header{
+			"Content-Type": {`application/vnd.api+json; ext="https://example.com/ext/other"`},
+		})
+		Expect(rec.Code).To(Equal(http.StatusUnsupportedMediaType))
+		Expect(rec.Body.String()).To(ContainSubstring("extension https://example.com/ext/other is not supported"))
+	})
+
+	It("applies profiles to all responses", func() {
+		api.RegisterProfile(timestampsProfile{})
+		doRequest("GET", "/v1/posts/1", "", http.Header{})
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("Content-Type")).To(Equal(`application/vnd.api+json; profile="https://example.com/profile/timestamps"`))
+		Expect(rec.Body.String()).To(ContainSubstring(`"jsonapi":{"profile":["https://example.com/profile/timestamps"]}`))
+		Expect(rec.Body.String()).To(ContainSubstring(`"meta":{"timestamp":"2020-01-01T00:00:00Z"}`))
+	})
+
+	It("lists the atomic extension in responses to atomic operations", func() {
+		api.EnableAtomicOperations(nil)
+		doRequest("POST", "/v1/operations", `{"atomic:operations": [{"op": "add", "data": {"type": "posts", "attributes": {"title": "New"}}}]}`, http.Header{})
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("Content-Type")).To(Equal(`application/vnd.api+json; ext="https://jsonapi.org/ext/atomic"`))
+	})
+})
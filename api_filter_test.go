@@ -0,0 +1,124 @@
+package api2go
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type filteredSource struct {
+	fixtureSource
+	lastRequest Request
+}
+
+func (s *filteredSource) FindAll(req Request) (Responder, error) {
+	s.lastRequest = req
+	return s.fixtureSource.FindAll(req)
+}
+
+func (s *filteredSource) FilterableFields() map[string][]FilterOperator {
+	return map[string][]FilterOperator{
+		"title": {FilterEqual, FilterLike},
+		"value": {FilterLessThan, FilterGreaterThan, FilterIn},
+	}
+}
+
+// unfilteredSource does not implement FilterableFields and parses filters from the query parameters itself
+type unfilteredSource struct {
+	fixtureSource
+	lastRequest Request
+}
+
+func (s *unfilteredSource) FindAll(req Request) (Responder, error) {
+	s.lastRequest = req
+	return s.fixtureSource.FindAll(req)
+}
+
+var _ = Describe("Filtering", func() {
+	var (
+		api    *API
+		rec    *httptest.ResponseRecorder
+		source *filteredSource
+	)
+
+	BeforeEach(func() {
+		source = &filteredSource{fixtureSource: fixtureSource{posts: map[string]*Post{
+			"1": {ID: "1", Title: "Hello, World!"},
+		}}}
+		api = NewAPI("v1")
+		api.AddResource(Post{}, source)
+		rec = httptest.NewRecorder()
+	})
+
+	It("parses filter parameters with and without operators", func() {
+		req, err := http.NewRequest("GET", "/v1/posts?filter[title]=foo&filter[value][in]=1,2&filter[value][gt]=0", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(buildRequest(req).Filter).To(Equal([]Filter{
+			{Field: "title", Operator: FilterEqual, Values: []string{"foo"}, Parameter: "filter[title]"},
+			{Field: "value", Operator: FilterGreaterThan, Values: []string{"0"}, Parameter: "filter[value][gt]"},
+			{Field: "value", Operator: FilterIn, Values: []string{"1", "2"}, Parameter: "filter[value][in]"},
+		}))
+	})
+
+	It("passes allowed filters to FindAll", func() {
+		req, err := http.NewRequest("GET", "/v1/posts?filter[title][like]=Hello", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(source.lastRequest.Filter).To(Equal([]Filter{
+			{Field: "title", Operator: FilterLike, Values: []string{"Hello"}, Parameter: "filter[title][like]"},
+		}))
+	})
+
+	It("rejects fields that are not filterable", func() {
+		req, err := http.NewRequest("GET", "/v1/posts?filter[author]=1", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(rec.Body.Bytes()).To(MatchJSON(`{"errors":[{
+			"status": "400",
+			"title": "Invalid filter parameter",
+			"detail": "posts can not be filtered by author",
+			"source": {"parameter": "filter[author]"}
+		}]}`))
+	})
+
+	It("rejects operators that are not allowed for a field", func() {
+		req, err := http.NewRequest("GET", "/v1/posts?filter[title][lt]=b", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(rec.Body.Bytes()).To(MatchJSON(`{"errors":[{
+			"status": "400",
+			"title": "Invalid filter parameter",
+			"detail": "posts can not be filtered by title with operator lt",
+			"source": {"parameter": "filter[title][lt]"}
+		}]}`))
+	})
+
+	It("rejects unknown operators", func() {
+		req, err := http.NewRequest("GET", "/v1/posts?filter[title][regex]=.*", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(rec.Body.Bytes()).To(MatchJSON(`{"errors":[{
+			"status": "400",
+			"title": "Invalid filter parameter",
+			"detail": "unknown filter operator regex",
+			"source": {"parameter": "filter[title][regex]"}
+		}]}`))
+	})
+
+	It("passes all filters to resources without FilterableFields", func() {
+		unfiltered := &unfilteredSource{fixtureSource: source.fixtureSource}
+		api = NewAPI("v1")
+		api.AddResource(Post{}, unfiltered)
+		req, err := http.NewRequest("GET", "/v1/posts?filter[author][name]=Marvin", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(unfiltered.lastRequest.QueryParams).To(HaveKeyWithValue("filter[author][name]", []string{"Marvin"}))
+	})
+})
@@ -1,10 +1,16 @@
 package api2go
 
-// The CRUD interface MUST be implemented in order to use the api2go api.
-type CRUD interface {
+import "context"
+
+// The Finder interface is implemented by sources whose resources can be read. It is needed for the
+// read, update and relationship routes, because updates are applied to the found object.
+type Finder interface {
 	// FindOne returns an object by its ID
 	FindOne(ID string, req Request) (Responder, error)
+}
 
+// The Creator interface is implemented by sources whose resources can be created
+type Creator interface {
 	// Create a new object. Newly created object/struct must be in Responder.
 	// Possible status codes are:
 	// - 201 Created: Resource was created and needs to be returned
@@ -12,20 +18,36 @@ type CRUD interface {
 	// - 204 No Content: Resource created with a client generated ID, and no fields were modified by
 	//   the server
 	Create(obj interface{}, req Request) (Responder, error)
+}
 
-	// Delete an object
+// The Updater interface is implemented by sources whose resources can be updated, which also needs Finder
+type Updater interface {
+	// Update an object
 	// Possible status codes are:
 	// - 200 OK: Update successful, however some field(s) were changed, returns updates source
 	// - 202 Accepted: Processing is delayed, return nothing
 	// - 204 No Content: Update was successful, no fields were changed by the server, return nothing
-	Delete(id string, req Request) (Responder, error)
+	Update(obj interface{}, req Request) (Responder, error)
+}
 
-	// Update an object
+// The Deleter interface is implemented by sources whose resources can be deleted
+type Deleter interface {
+	// Delete an object
 	// Possible status codes are:
-	// - 200 OK: Deletion was a success, returns meta information, currently not implemented! Do not use this
+	// - 200 OK: Deletion was a success, returns meta information
 	// - 202 Accepted: Processing is delayed, return nothing
 	// - 204 No Content: Deletion was successful, return nothing
-	Update(obj interface{}, req Request) (Responder, error)
+	Delete(id string, req Request) (Responder, error)
+}
+
+// The CRUD interface combines Finder, Creator, Updater and Deleter for sources that support all of them.
+// Sources passed to AddResource only need to implement the interfaces of the supported operations, the
+// routes of all others are not registered and answered with 405 Method Not Allowed.
+type CRUD interface {
+	Finder
+	Creator
+	Updater
+	Deleter
 }
 
 // ContentMarshaler controls how requests from clients are unmarshaled
@@ -48,12 +70,113 @@ type PaginatedFindAll interface {
 	PaginatedFindAll(req Request) (totalCount uint, response Responder, err error)
 }
 
+// The CursorPaginatedFindAll interface can be optionally implemented to fetch a subset of all records
+// with opaque cursors instead of page numbers or offsets, as described in the cursor pagination profile
+// https://jsonapi.org/profiles/ethanresnick/cursor-pagination/. It is used for requests with
+// page[after], page[before] or only page[size]. The returned cursors are used to generate the
+// next and prev links, so that no total count is needed.
+type CursorPaginatedFindAll interface {
+	CursorPaginatedFindAll(req Request) (cursors Cursors, response Responder, err error)
+}
+
 // The FindAll interface can be optionally implemented to fetch all records at once.
 type FindAll interface {
 	// FindAll returns all objects
 	FindAll(req Request) (Responder, error)
 }
 
+// The FindOneContext interface can be optionally implemented and is preferred over Finder.
+// ctx is the context of the request, which is canceled when the client goes away.
+type FindOneContext interface {
+	FindOneContext(ctx context.Context, ID string, req Request) (Responder, error)
+}
+
+// The CreateContext interface can be optionally implemented and is preferred over Creator
+type CreateContext interface {
+	CreateContext(ctx context.Context, obj interface{}, req Request) (Responder, error)
+}
+
+// The UpdateContext interface can be optionally implemented and is preferred over Updater
+type UpdateContext interface {
+	UpdateContext(ctx context.Context, obj interface{}, req Request) (Responder, error)
+}
+
+// The DeleteContext interface can be optionally implemented and is preferred over Deleter
+type DeleteContext interface {
+	DeleteContext(ctx context.Context, id string, req Request) (Responder, error)
+}
+
+// The FindAllContext interface can be optionally implemented and is preferred over FindAll
+type FindAllContext interface {
+	FindAllContext(ctx context.Context, req Request) (Responder, error)
+}
+
+// The PaginatedFindAllContext interface can be optionally implemented and is preferred over PaginatedFindAll
+type PaginatedFindAllContext interface {
+	PaginatedFindAllContext(ctx context.Context, req Request) (totalCount uint, response Responder, err error)
+}
+
+// The CursorPaginatedFindAllContext interface can be optionally implemented and is preferred over CursorPaginatedFindAll
+type CursorPaginatedFindAllContext interface {
+	CursorPaginatedFindAllContext(ctx context.Context, req Request) (cursors Cursors, response Responder, err error)
+}
+
+// The BulkCreator interface can be optionally implemented to create multiple objects with one POST request
+// whose `data` is an array. All unmarshalled objects are passed to BulkCreate at once, so that either all or
+// none of them can be created. Errors for single objects should be returned as HTTPError with an Error for
+// each of them, using the position in the request as ErrorSource.Pointer, e.g. `/data/3/attributes/name`.
+// The possible status codes are the same as for Create, with 201 Created all new objects must be returned
+// in the Responder.
+type BulkCreator interface {
+	BulkCreate(objs []interface{}, req Request) (Responder, error)
+}
+
+// The Validator interface can be optionally implemented by models to validate themselves after they have been
+// unmarshalled for Create or Update. Every returned Error should point to the invalid member with its
+// ErrorSource.Pointer, e.g. `/data/attributes/user-name`. Errors without status are sent as 422 Unprocessable
+// Entity, and Create or Update is not called if there are any.
+type Validator interface {
+	Validate() []Error
+}
+
+// The ObjectValidator interface can be optionally implemented by sources to validate objects after they have
+// been unmarshalled for Create or Update, for checks that need the request or other resources. The returned
+// errors are handled like the ones of Validator.
+type ObjectValidator interface {
+	ValidateObject(obj interface{}, req Request) []Error
+}
+
+// The Transactor interface can be passed to EnableAtomicOperations to run all operations of a request
+// in one transaction. Begin returns the context that is passed to the sources through Request.Context,
+// so that they can find the transaction in it. Rollback is called if any operation fails, Commit otherwise.
+type Transactor interface {
+	Begin(ctx context.Context) (context.Context, error)
+	Commit(ctx context.Context) error
+	Rollback(ctx context.Context) error
+}
+
+// The Versioner interface can be optionally implemented by models of resources with WithETags to
+// use their version as entity tag instead of a hash of the marshalled document. The version must change
+// with every update of the model, the include and fields parameters are added to it.
+type Versioner interface {
+	Version() string
+}
+
+// The SortableFields interface can be optionally implemented to restrict the fields that can be used
+// in the sort query parameter. Requests with any other sort field are rejected with 400 Bad Request
+// before FindAll or PaginatedFindAll is called.
+type SortableFields interface {
+	SortableFields() []string
+}
+
+// The FilterableFields interface can be optionally implemented to restrict the fields and operators
+// that can be used in filter query parameters. FilterableFields returns the allowed operators for every
+// filterable field. Requests with any other filter are rejected with 400 Bad Request before FindAll or
+// PaginatedFindAll is called.
+type FilterableFields interface {
+	FilterableFields() map[string][]FilterOperator
+}
+
 // The Responder interface is used by all Resource Methods as a container for the Response.
 // Metadata is additional Metadata. You can put anything you like into it, see jsonapi spec.
 // Result returns the actual payload. For FindOne, put only one entry in it.
@@ -91,11 +91,12 @@ var _ = Describe("Test interface api type casting", func() {
 		rec = httptest.NewRecorder()
 	})
 
-	It("FindAll returns 404 for simple CRUD", func() {
+	It("FindAll returns 405 for simple CRUD", func() {
 		req, err := http.NewRequest("GET", "/v1/someDatas", nil)
 		Expect(err).ToNot(HaveOccurred())
 		api.Handler().ServeHTTP(rec, req)
-		Expect(rec.Code).To(Equal(http.StatusNotFound))
+		Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+		Expect(rec.Header().Get("Allow")).To(Equal("POST,OPTIONS"))
 	})
 
 	It("Works for a normal FindOne", func() {
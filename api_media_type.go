@@ -0,0 +1,138 @@
+package api2go
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SetStrictMediaTypes enables the media type rules of JSON:API, which are disabled by default so that
+// unknown media types fall back to the JSON:API media type. Requests whose Content-Type is the JSON:API
+// media type with parameters other than ext and profile are rejected with 415 Unsupported Media Type,
+// requests whose Accept header only contains the JSON:API media type with such parameters with
+// 406 Not Acceptable.
+func (api *API) SetStrictMediaTypes(strict bool) {
+	api.mediaTypes.strict = strict
+}
+
+// checkMediaTypes checks the Content-Type and Accept headers of r if strict media types are enabled
+func (api *API) checkMediaTypes(r *http.Request) error {
+	if !api.mediaTypes.strict {
+		return nil
+	}
+
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, params, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return newMediaTypeError(http.StatusUnsupportedMediaType, fmt.Sprintf("invalid Content-Type %q", contentType))
+		}
+
+		if parameter := unsupportedParameter(params); mediaType == defaultContentTypHeader && parameter != "" {
+			return newMediaTypeError(http.StatusUnsupportedMediaType,
+				fmt.Sprintf("media type parameter %s is not supported, only ext and profile are allowed", parameter))
+		}
+
+		if uri := api.unsupportedExtension(params); mediaType == defaultContentTypHeader && uri != "" {
+			return newMediaTypeError(http.StatusUnsupportedMediaType, fmt.Sprintf("extension %s is not supported", uri))
+		}
+	}
+
+	found, acceptable := false, false
+	for _, entry := range acceptEntries(r) {
+		mediaType, params, err := mime.ParseMediaType(entry)
+		if err != nil || mediaType != defaultContentTypHeader {
+			continue
+		}
+
+		found = true
+		if unsupportedParameter(params) == "" && api.unsupportedExtension(params) == "" {
+			acceptable = true
+		}
+	}
+
+	if found && !acceptable {
+		return newMediaTypeError(http.StatusNotAcceptable,
+			"every JSON:API media type in Accept has parameters other than ext and profile or unsupported extensions")
+	}
+
+	return nil
+}
+
+// unsupportedParameter returns the first media type parameter other than ext and profile, the quality
+// of Accept entries is no media type parameter
+func unsupportedParameter(params map[string]string) string {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		if name != "ext" && name != "profile" && name != "q" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	if len(names) == 0 {
+		return ""
+	}
+
+	return names[0]
+}
+
+// unsupportedExtension returns the first URI of the ext parameter that is not a registered extension
+func (api *API) unsupportedExtension(params map[string]string) string {
+	for _, uri := range strings.Fields(params["ext"]) {
+		if !api.mediaTypes.supports(uri) {
+			return uri
+		}
+	}
+
+	return ""
+}
+
+// acceptEntries returns the media ranges of all Accept headers of r
+func acceptEntries(r *http.Request) []string {
+	var entries []string
+	for _, header := range r.Header["Accept"] {
+		for _, entry := range strings.Split(header, ",") {
+			if entry = strings.TrimSpace(entry); entry != "" {
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	return entries
+}
+
+// mediaTypeParameters returns the URIs of the ext and profile parameters of the JSON:API media type of
+// the Content-Type header, or of the first Accept entry with the JSON:API media type for requests
+// without Content-Type
+func mediaTypeParameters(r *http.Request) (extensions, profiles []string) {
+	entries := acceptEntries(r)
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		entries = []string{contentType}
+	}
+
+	for _, entry := range entries {
+		mediaType, params, err := mime.ParseMediaType(entry)
+		if err != nil || mediaType != defaultContentTypHeader {
+			continue
+		}
+
+		return strings.Fields(params["ext"]), strings.Fields(params["profile"])
+	}
+
+	return nil, nil
+}
+
+func newMediaTypeError(status int, detail string) HTTPError {
+	title := http.StatusText(status)
+	httpErr := NewHTTPError(nil, title, status)
+	httpErr.Errors = []Error{{
+		Status: strconv.Itoa(status),
+		Title:  title,
+		Detail: detail,
+	}}
+
+	return httpErr
+}
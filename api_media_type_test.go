@@ -0,0 +1,129 @@
+package api2go
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// requestRecordingSource records the requests to FindOne and Create
+type requestRecordingSource struct {
+	*fixtureSource
+	requests []Request
+}
+
+func (s *requestRecordingSource) FindOne(ID string, req Request) (Responder, error) {
+	s.requests = append(s.requests, req)
+	return s.fixtureSource.FindOne(ID, req)
+}
+
+func (s *requestRecordingSource) Create(obj interface{}, req Request) (Responder, error) {
+	s.requests = append(s.requests, req)
+	return s.fixtureSource.Create(obj, req)
+}
+
+var _ = Describe("Media types", func() {
+	var (
+		api    *API
+		rec    *httptest.ResponseRecorder
+		source *requestRecordingSource
+	)
+
+	BeforeEach(func() {
+		source = &requestRecordingSource{fixtureSource: &fixtureSource{posts: map[string]*Post{"1": {ID: "1", Title: "Hello, World!"}}}}
+		api = NewAPI("v1")
+		api.AddResource(Post{}, source)
+		rec = httptest.NewRecorder()
+	})
+
+	doRequest := func(method, url, body string, header http.Header) {
+		req, err := http.NewRequest(method, url, strings.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+		req.Header = header
+		api.Handler().ServeHTTP(rec, req)
+	}
+
+	post := `{"data": {"type": "posts", "attributes": {"title": "New"}}}`
+
+	It("ignores media type parameters by default", func() {
+		doRequest("POST", "/v1/posts", post, http.Header{
+			"Content-Type": {"application/vnd.api+json; charset=utf-8"},
+			"Accept":       {"application/vnd.api+json; version=1"},
+		})
+		Expect(rec.Code).To(Equal(http.StatusCreated))
+	})
+
+	It("passes ext and profile of the Content-Type to the source", func() {
+		doRequest("POST", "/v1/posts", post, http.Header{
+			"Content-Type": {`application/vnd.api+json; ext="https://example.com/ext/a https://example.com/ext/b"; profile="https://example.com/profile"`},
+		})
+		Expect(rec.Code).To(Equal(http.StatusCreated))
+		Expect(source.requests).To(HaveLen(1))
+		Expect(source.requests[0].Extensions).To(Equal([]string{"https://example.com/ext/a", "https://example.com/ext/b"}))
+		Expect(source.requests[0].Profiles).To(Equal([]string{"https://example.com/profile"}))
+	})
+
+	It("passes ext and profile of the Accept header to the source without Content-Type", func() {
+		doRequest("GET", "/v1/posts/1", "", http.Header{
+			"Accept": {`text/html, application/vnd.api+json; profile="https://example.com/profile"`},
+		})
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(source.requests).To(HaveLen(1))
+		Expect(source.requests[0].Extensions).To(BeEmpty())
+		Expect(source.requests[0].Profiles).To(Equal([]string{"https://example.com/profile"}))
+	})
+
+	Context("strict", func() {
+		BeforeEach(func() {
+			api.SetStrictMediaTypes(true)
+		})
+
+		It("accepts the JSON:API media type with ext and profile", func() {
+			api.RegisterExtension(versionExtension{})
+			doRequest("POST", "/v1/posts", post, http.Header{
+				"Content-Type": {`application/vnd.api+json; profile="https://example.com/profile"`},
+				"Accept":       {`application/vnd.api+json; ext="https://example.com/ext/version"`},
+			})
+			Expect(rec.Code).To(Equal(http.StatusCreated))
+		})
+
+		It("responds with 415 Unsupported Media Type to other parameters of the Content-Type", func() {
+			doRequest("POST", "/v1/posts", post, http.Header{"Content-Type": {"application/vnd.api+json; charset=utf-8"}})
+			Expect(rec.Code).To(Equal(http.StatusUnsupportedMediaType))
+			Expect(rec.Body.Bytes()).To(MatchJSON(`{"errors": [{
+				"status": "415",
+				"title": "Unsupported Media Type",
+				"detail": "media type parameter charset is not supported, only ext and profile are allowed"
+			}]}`))
+			Expect(source.requests).To(BeEmpty())
+		})
+
+		It("responds with 406 Not Acceptable if all JSON:API media types of Accept have other parameters", func() {
+			doRequest("GET", "/v1/posts/1", "", http.Header{
+				"Accept": {"application/vnd.api+json; version=1", "application/vnd.api+json; charset=utf-8"},
+			})
+			Expect(rec.Code).To(Equal(http.StatusNotAcceptable))
+			Expect(rec.Body.Bytes()).To(MatchJSON(`{"errors": [{
+				"status": "406",
+				"title": "Not Acceptable",
+				"detail": "every JSON:API media type in Accept has parameters other than ext and profile or unsupported extensions"
+			}]}`))
+			Expect(source.requests).To(BeEmpty())
+		})
+
+		It("accepts requests if one JSON:API media type of Accept has no other parameters", func() {
+			doRequest("GET", "/v1/posts/1", "", http.Header{
+				"Accept": {"application/vnd.api+json; version=1, application/vnd.api+json; q=0.5"},
+			})
+			Expect(rec.Code).To(Equal(http.StatusOK))
+		})
+
+		It("accepts other media types", func() {
+			doRequest("GET", "/v1/posts/1", "", http.Header{"Accept": {"application/json; charset=utf-8"}})
+			Expect(rec.Code).To(Equal(http.StatusOK))
+		})
+	})
+})
@@ -0,0 +1,96 @@
+package api2go
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Middlewares", func() {
+	var (
+		api    *API
+		rec    *httptest.ResponseRecorder
+		source *contextSource
+		calls  []string
+		routes []Route
+	)
+
+	recordingMiddleware := func(name string) Middleware {
+		return func(w http.ResponseWriter, r *http.Request, route Route, next http.HandlerFunc) {
+			calls = append(calls, name)
+			routes = append(routes, route)
+			next(w, r)
+		}
+	}
+
+	BeforeEach(func() {
+		calls = []string{}
+		routes = []Route{}
+		source = &contextSource{fixtureSource: fixtureSource{posts: map[string]*Post{
+			"1": {ID: "1", Title: "Hello, World!"},
+		}}}
+		api = NewAPI("v1")
+		api.Use(recordingMiddleware("api"))
+		api.AddResource(Post{}, source, WithMiddleware(recordingMiddleware("posts")))
+		api.AddResource(User{}, &userSource{})
+		api.Use(recordingMiddleware("late"))
+		rec = httptest.NewRecorder()
+	})
+
+	doRequest := func(method, url, body string) {
+		req, err := http.NewRequest(method, url, strings.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+	}
+
+	It("calls the api middlewares before the resource middlewares", func() {
+		doRequest("GET", "/v1/posts", "")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(calls).To(Equal([]string{"api", "late", "posts"}))
+		Expect(routes[2]).To(Equal(Route{Resource: "posts", Operation: OperationIndex}))
+	})
+
+	It("calls resource middlewares only for their resource", func() {
+		doRequest("DELETE", "/v1/users/1", "")
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(calls).To(Equal([]string{"api", "late"}))
+		Expect(routes[0]).To(Equal(Route{Resource: "users", Operation: OperationDelete}))
+	})
+
+	It("passes the operation and relation to the middlewares", func() {
+		doRequest("GET", "/v1/posts/1", "")
+		doRequest("POST", "/v1/posts", `{"data": {"type": "posts", "attributes": {"title": "New Post"}}}`)
+		doRequest("PATCH", "/v1/posts/1", `{"data": {"type": "posts", "id": "1", "attributes": {"title": "New Title"}}}`)
+		doRequest("GET", "/v1/posts/1/relationships/comments", "")
+		doRequest("DELETE", "/v1/posts/1", "")
+
+		Expect(routes).To(HaveLen(15))
+		Expect(routes[2]).To(Equal(Route{Resource: "posts", Operation: OperationRead}))
+		Expect(routes[5]).To(Equal(Route{Resource: "posts", Operation: OperationCreate}))
+		Expect(routes[8]).To(Equal(Route{Resource: "posts", Operation: OperationUpdate}))
+		Expect(routes[11]).To(Equal(Route{Resource: "posts", Operation: OperationRelationship, Relation: "comments"}))
+		Expect(routes[14]).To(Equal(Route{Resource: "posts", Operation: OperationDelete}))
+	})
+
+	It("can stop a request", func() {
+		api.Use(func(w http.ResponseWriter, r *http.Request, route Route, next http.HandlerFunc) {
+			api.handleError(NewHTTPError(nil, "Unauthorized", http.StatusUnauthorized), w, r, route, api.marshalers)
+		})
+		doRequest("DELETE", "/v1/posts/1", "")
+		Expect(rec.Code).To(Equal(http.StatusUnauthorized))
+		Expect(source.posts).To(HaveKey("1"))
+	})
+
+	It("can enrich the context of a request", func() {
+		api.Use(func(w http.ResponseWriter, r *http.Request, route Route, next http.HandlerFunc) {
+			next(w, r.WithContext(context.WithValue(r.Context(), contextKey("user"), route.Resource)))
+		})
+		doRequest("GET", "/v1/posts/1", "")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(source.values).To(Equal([]interface{}{"posts"}))
+	})
+})
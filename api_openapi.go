@@ -0,0 +1,449 @@
+package api2go
+
+import (
+	"encoding"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/manyminds/api2go/jsonapi"
+)
+
+const openAPIVersion = "3.0.3"
+
+// OpenAPI returns an OpenAPI 3 document that describes the routes of all registered resources.
+// The schemas of the attributes are derived by reflection from the resource prototypes,
+// relationships from their references. The returned document can be changed before it is
+// marshalled, e.g. to set the title and version of `info`.
+func (api *API) OpenAPI() map[string]interface{} {
+	paths := map[string]interface{}{}
+	schemas := map[string]interface{}{
+		"ResourceIdentifier": map[string]interface{}{
+			"type":     "object",
+			"required": []string{"type", "id"},
+			"properties": map[string]interface{}{
+				"type": map[string]interface{}{"type": "string"},
+				"id":   map[string]interface{}{"type": "string"},
+			},
+		},
+		"Errors": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"errors": map[string]interface{}{
+					"type":  "array",
+					"items": openAPISchema(reflect.TypeOf(Error{})),
+				},
+			},
+		},
+	}
+
+	for i := range api.resources {
+		api.resources[i].openAPISchemas(schemas)
+	}
+	for i := range api.resources {
+		api.resources[i].openAPIPaths(api.prefix, paths, schemas)
+	}
+
+	doc := map[string]interface{}{
+		"openapi": openAPIVersion,
+		"info": map[string]interface{}{
+			"title":   "api2go",
+			"version": "1.0.0",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+		},
+	}
+
+	if baseURL := api.info.GetBaseURL(); baseURL != "" {
+		doc["servers"] = []interface{}{map[string]interface{}{"url": baseURL}}
+	}
+
+	return doc
+}
+
+// EnableOpenAPI serves the document returned by OpenAPI under prefix + "openapi.json"
+func (api *API) EnableOpenAPI() {
+	api.route("GET", api.prefix+"openapi.json", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		content, err := json.Marshal(api.OpenAPI())
+		if err != nil {
+			api.handleError(err, w, r, Route{}, api.marshalers)
+			return
+		}
+
+		writeResult(w, content, http.StatusOK, "application/json")
+	})
+}
+
+func (res *resource) openAPISchemas(schemas map[string]interface{}) {
+	attributes := map[string]interface{}{}
+	for name, field := range jsonapi.AttributeFields(res.prototype()) {
+		attributes[name] = openAPISchema(field.Type)
+	}
+
+	relationships := map[string]interface{}{}
+	for _, relation := range res.references() {
+		var data interface{}
+		if isToMany(relation) {
+			data = map[string]interface{}{"type": "array", "items": openAPIRef("ResourceIdentifier")}
+		} else {
+			data = map[string]interface{}{"allOf": []interface{}{openAPIRef("ResourceIdentifier")}, "nullable": true}
+		}
+
+		relationships[relation.Name] = map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"data": data},
+		}
+	}
+
+	schemas[res.name] = map[string]interface{}{
+		"type":     "object",
+		"required": []string{"type"},
+		"properties": map[string]interface{}{
+			"type":          map[string]interface{}{"type": "string", "enum": []string{res.name}},
+			"id":            map[string]interface{}{"type": "string"},
+			"attributes":    map[string]interface{}{"type": "object", "properties": attributes},
+			"relationships": map[string]interface{}{"type": "object", "properties": relationships},
+		},
+	}
+}
+
+func (res *resource) openAPIPaths(prefix string, paths map[string]interface{}, schemas map[string]interface{}) {
+	document := openAPIDocument(openAPIRef(res.name))
+	listDocument := openAPIDocument(map[string]interface{}{"type": "array", "items": openAPIRef(res.name)})
+
+	collection := map[string]interface{}{}
+	if res.canCreate() || res.isBulkCreator() {
+		collection["post"] = openAPIOperation("Create "+res.name, nil, document, map[string]interface{}{
+			"201": openAPIResponse("Created", document),
+			"202": openAPIResponse("Accepted", nil),
+			"204": openAPIResponse("No Content", nil),
+		})
+	}
+
+	if res.isFindAll() || res.isPaginated() || res.isCursorPaginated() {
+		parameters := []interface{}{
+			openAPIQueryParameter("include"),
+			openAPIQueryParameter("sort"),
+			openAPIDeepObjectParameter("fields"),
+			openAPIDeepObjectParameter("filter"),
+		}
+		if res.isPaginated() || res.isCursorPaginated() {
+			parameters = append(parameters, openAPIDeepObjectParameter("page"))
+		}
+
+		collection["get"] = openAPIOperation("List "+res.name, parameters, nil, map[string]interface{}{
+			"200": openAPIResponse("OK", listDocument),
+		})
+	}
+	if len(collection) > 0 {
+		paths[prefix+res.name] = collection
+	}
+
+	idParameter := map[string]interface{}{
+		"name":     "id",
+		"in":       "path",
+		"required": true,
+		"schema":   map[string]interface{}{"type": "string"},
+	}
+
+	item := map[string]interface{}{}
+	if res.canFind() {
+		item["get"] = openAPIOperation("Read "+res.name, []interface{}{
+			openAPIQueryParameter("include"),
+			openAPIDeepObjectParameter("fields"),
+		}, nil, map[string]interface{}{
+			"200": openAPIResponse("OK", document),
+		})
+	}
+	if res.canUpdate() {
+		item["patch"] = openAPIOperation("Update "+res.name, nil, document, map[string]interface{}{
+			"200": openAPIResponse("OK", document),
+			"202": openAPIResponse("Accepted", nil),
+			"204": openAPIResponse("No Content", nil),
+		})
+	}
+	if res.canDelete() {
+		item["delete"] = openAPIOperation("Delete "+res.name, nil, nil, map[string]interface{}{
+			"200": openAPIResponse("OK", nil),
+			"202": openAPIResponse("Accepted", nil),
+			"204": openAPIResponse("No Content", nil),
+		})
+	}
+	if len(item) > 0 {
+		item["parameters"] = []interface{}{idParameter}
+		paths[prefix+res.name+"/{id}"] = item
+	}
+
+	_, editToMany := res.prototype().(jsonapi.EditToManyRelations)
+	for _, relation := range res.references() {
+		// related resources that are not registered are described by their identifiers only
+		relatedSchema := "ResourceIdentifier"
+		if _, ok := schemas[relation.Type]; ok {
+			relatedSchema = relation.Type
+		}
+
+		var linkage, related map[string]interface{}
+		if isToMany(relation) {
+			linkage = openAPIDocument(map[string]interface{}{"type": "array", "items": openAPIRef("ResourceIdentifier")})
+			related = openAPIDocument(map[string]interface{}{"type": "array", "items": openAPIRef(relatedSchema)})
+		} else {
+			linkage = openAPIDocument(map[string]interface{}{"allOf": []interface{}{openAPIRef("ResourceIdentifier")}, "nullable": true})
+			related = openAPIDocument(map[string]interface{}{"allOf": []interface{}{openAPIRef(relatedSchema)}, "nullable": true})
+		}
+
+		paths[prefix+res.name+"/{id}/"+relation.Name] = map[string]interface{}{
+			"parameters": []interface{}{idParameter},
+			"get": openAPIOperation("Read "+relation.Name+" of "+res.name, []interface{}{
+				openAPIQueryParameter("include"),
+				openAPIDeepObjectParameter("fields"),
+			}, nil, map[string]interface{}{
+				"200": openAPIResponse("OK", related),
+			}),
+		}
+
+		if !res.canFind() {
+			continue
+		}
+
+		relationship := map[string]interface{}{
+			"parameters": []interface{}{idParameter},
+			"get": openAPIOperation("Read relationship "+relation.Name+" of "+res.name, nil, nil, map[string]interface{}{
+				"200": openAPIResponse("OK", linkage),
+			}),
+		}
+		if res.canUpdate() {
+			relationship["patch"] = openAPIOperation("Replace relationship "+relation.Name+" of "+res.name, nil, linkage, map[string]interface{}{
+				"204": openAPIResponse("No Content", nil),
+			})
+		}
+		if editToMany && isToMany(relation) && res.canUpdate() {
+			relationship["post"] = openAPIOperation("Add to relationship "+relation.Name+" of "+res.name, nil, linkage, map[string]interface{}{
+				"204": openAPIResponse("No Content", nil),
+			})
+			relationship["delete"] = openAPIOperation("Delete from relationship "+relation.Name+" of "+res.name, nil, linkage, map[string]interface{}{
+				"204": openAPIResponse("No Content", nil),
+			})
+		}
+		paths[prefix+res.name+"/{id}/relationships/"+relation.Name] = relationship
+	}
+}
+
+func openAPIRef(name string) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
+
+func openAPIDocument(data map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "object",
+		"required":   []string{"data"},
+		"properties": map[string]interface{}{"data": data},
+	}
+}
+
+func openAPIContent(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		defaultContentTypHeader: map[string]interface{}{"schema": schema},
+	}
+}
+
+func openAPIResponse(description string, schema map[string]interface{}) map[string]interface{} {
+	response := map[string]interface{}{"description": description}
+	if schema != nil {
+		response["content"] = openAPIContent(schema)
+	}
+
+	return response
+}
+
+// openAPIOperation adds the error response that every operation can return
+func openAPIOperation(summary string, parameters []interface{}, requestBody map[string]interface{}, responses map[string]interface{}) map[string]interface{} {
+	responses["default"] = openAPIResponse("Error", openAPIRef("Errors"))
+	operation := map[string]interface{}{
+		"summary":   summary,
+		"responses": responses,
+	}
+
+	if len(parameters) > 0 {
+		operation["parameters"] = parameters
+	}
+
+	if requestBody != nil {
+		operation["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  openAPIContent(requestBody),
+		}
+	}
+
+	return operation
+}
+
+func openAPIQueryParameter(name string) map[string]interface{} {
+	return map[string]interface{}{
+		"name":   name,
+		"in":     "query",
+		"schema": map[string]interface{}{"type": "string"},
+	}
+}
+
+// openAPIDeepObjectParameter describes the parameter families like page[size] or fields[posts]
+func openAPIDeepObjectParameter(name string) map[string]interface{} {
+	return map[string]interface{}{
+		"name":    name,
+		"in":      "query",
+		"style":   "deepObject",
+		"explode": true,
+		"schema": map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": map[string]interface{}{"type": "string"},
+		},
+	}
+}
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	jsonMarshalerType   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	openAPIIntegerKinds = map[reflect.Kind]string{
+		reflect.Int: "int64", reflect.Int8: "int32", reflect.Int16: "int32", reflect.Int32: "int32", reflect.Int64: "int64",
+		reflect.Uint: "int64", reflect.Uint8: "int32", reflect.Uint16: "int32", reflect.Uint32: "int64", reflect.Uint64: "int64",
+	}
+)
+
+// openAPISchema returns the schema of the json encoding of a type
+func openAPISchema(t reflect.Type) map[string]interface{} {
+	return openAPITypeSchema(t, map[reflect.Type]bool{})
+}
+
+// openAPITypeSchema returns the schema of a type inside of the structs in visiting. Recursive structs
+// get an empty schema where they repeat, their schema would be infinite otherwise.
+func openAPITypeSchema(t reflect.Type, visiting map[reflect.Type]bool) map[string]interface{} {
+	if t.Kind() == reflect.Ptr {
+		schema := openAPITypeSchema(t.Elem(), visiting)
+		schema["nullable"] = true
+		return schema
+	}
+
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	if t.Implements(jsonMarshalerType) || reflect.PtrTo(t).Implements(jsonMarshalerType) {
+		// null types like sql.NullString wrapped with a json.Marshaler are a value and a Valid flag
+		if value, ok := nullableValueType(t); ok {
+			schema := openAPITypeSchema(value, visiting)
+			schema["nullable"] = true
+			return schema
+		}
+
+		return map[string]interface{}{}
+	}
+
+	if t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType) {
+		return map[string]interface{}{"type": "string"}
+	}
+
+	if format, ok := openAPIIntegerKinds[t.Kind()]; ok {
+		return map[string]interface{}{"type": "integer", "format": format}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Float32:
+		return map[string]interface{}{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return map[string]interface{}{"type": "number", "format": "double"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+
+		return map[string]interface{}{"type": "array", "items": openAPITypeSchema(t.Elem(), visiting)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": openAPITypeSchema(t.Elem(), visiting)}
+	case reflect.Struct:
+		if visiting[t] {
+			return map[string]interface{}{}
+		}
+
+		visiting[t] = true
+		defer delete(visiting, t)
+
+		properties := map[string]interface{}{}
+		openAPIStructProperties(t, properties, visiting)
+		return map[string]interface{}{"type": "object", "properties": properties}
+	}
+
+	return map[string]interface{}{}
+}
+
+// openAPIStructProperties adds the properties of a struct like encoding/json marshals them
+func openAPIStructProperties(t reflect.Type, properties map[string]interface{}, visiting map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(field.Tag.Get("json"), ",")
+		if tag[0] == "-" {
+			continue
+		}
+
+		if field.Anonymous && tag[0] == "" {
+			fieldType := field.Type
+			if fieldType.Kind() == reflect.Ptr {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				// structs that embed themselves through a pointer are only expanded once
+				if !visiting[fieldType] {
+					visiting[fieldType] = true
+					openAPIStructProperties(fieldType, properties, visiting)
+					delete(visiting, fieldType)
+				}
+				continue
+			}
+		}
+
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := field.Name
+		if tag[0] != "" {
+			name = tag[0]
+		}
+
+		properties[name] = openAPITypeSchema(field.Type, visiting)
+	}
+}
+
+// nullableValueType returns the type of the value of structs that consist of a value and
+// a Valid flag, like sql.NullString or structs embedding it
+func nullableValueType(t reflect.Type) (reflect.Type, bool) {
+	if t.Kind() != reflect.Struct {
+		return nil, false
+	}
+
+	if t.NumField() == 1 && t.Field(0).Anonymous {
+		return nullableValueType(t.Field(0).Type)
+	}
+
+	if t.NumField() != 2 {
+		return nil, false
+	}
+
+	valid, value := t.Field(0), t.Field(1)
+	if valid.Name != "Valid" {
+		valid, value = value, valid
+	}
+	if valid.Name != "Valid" || valid.Type.Kind() != reflect.Bool {
+		return nil, false
+	}
+
+	return value.Type, true
+}
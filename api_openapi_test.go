@@ -0,0 +1,103 @@
+package api2go
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// node is a recursive attribute type
+type node struct {
+	Name     string  `json:"name"`
+	Children []*node `json:"children"`
+}
+
+var _ = Describe("OpenAPI", func() {
+	var (
+		api *API
+		doc map[string]interface{}
+	)
+
+	// lookup follows a path of keys through the nested maps of the document
+	lookup := func(keys ...string) interface{} {
+		var current interface{} = doc
+		for _, key := range keys {
+			Expect(current).To(HaveKey(key))
+			current = current.(map[string]interface{})[key]
+		}
+		return current
+	}
+
+	BeforeEach(func() {
+		api = NewAPIWithBaseURL("v1", "http://localhost")
+		api.AddResource(Post{}, &fixtureSource{map[string]*Post{}, false})
+		api.AddResource(Comment{}, &commentSource{})
+		api.AddResource(User{}, &userSource{})
+		api.EnableOpenAPI()
+
+		rec := httptest.NewRecorder()
+		req, err := http.NewRequest("GET", "/v1/openapi.json", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(json.Unmarshal(rec.Body.Bytes(), &doc)).To(Succeed())
+	})
+
+	It("stops at recursive types", func() {
+		Expect(openAPISchema(reflect.TypeOf(&node{}))).To(Equal(map[string]interface{}{
+			"type":     "object",
+			"nullable": true,
+			"properties": map[string]interface{}{
+				"name": map[string]interface{}{"type": "string"},
+				"children": map[string]interface{}{
+					"type":  "array",
+					"items": map[string]interface{}{"nullable": true},
+				},
+			},
+		}))
+	})
+
+	It("describes the api", func() {
+		Expect(doc["openapi"]).To(Equal("3.0.3"))
+		Expect(doc["servers"]).To(Equal([]interface{}{map[string]interface{}{"url": "http://localhost"}}))
+	})
+
+	It("derives the schemas of attributes", func() {
+		attributes := lookup("components", "schemas", "posts", "properties", "attributes", "properties")
+		Expect(attributes).To(Equal(map[string]interface{}{
+			"title": map[string]interface{}{"type": "string"},
+			"value": map[string]interface{}{"type": "number", "format": "double", "nullable": true},
+		}))
+	})
+
+	It("derives the schemas of relationships", func() {
+		relationships := lookup("components", "schemas", "posts", "properties", "relationships", "properties")
+		Expect(relationships).To(HaveKey("author"))
+		Expect(lookup("components", "schemas", "posts", "properties", "relationships", "properties", "comments", "properties", "data", "type")).To(Equal("array"))
+		Expect(lookup("components", "schemas", "posts", "properties", "relationships", "properties", "author", "properties", "data", "nullable")).To(Equal(true))
+	})
+
+	It("describes all routes of the resources", func() {
+		Expect(lookup("paths", "/v1/posts")).To(HaveKey("get"))
+		Expect(lookup("paths", "/v1/posts")).To(HaveKey("post"))
+		Expect(lookup("paths", "/v1/posts", "get", "parameters")).To(ContainElement(HaveKeyWithValue("name", "page")))
+		Expect(lookup("paths", "/v1/posts/{id}")).To(HaveKey("patch"))
+		Expect(lookup("paths", "/v1/posts/{id}")).To(HaveKey("delete"))
+		Expect(lookup("paths", "/v1/posts/{id}/comments", "get", "responses", "200", "content", "application/vnd.api+json", "schema", "properties", "data", "items")).
+			To(Equal(map[string]interface{}{"$ref": "#/components/schemas/comments"}))
+	})
+
+	It("describes the to-many routes for EditToManyRelations", func() {
+		Expect(lookup("paths", "/v1/posts/{id}/relationships/comments")).To(HaveKey("post"))
+		Expect(lookup("paths", "/v1/posts/{id}/relationships/author")).ToNot(HaveKey("post"))
+	})
+
+	It("refers to identifiers for related resources that are not registered", func() {
+		Expect(lookup("paths", "/v1/posts/{id}/bananas", "get", "responses", "200", "content", "application/vnd.api+json", "schema", "properties", "data", "items")).
+			To(Equal(map[string]interface{}{"$ref": "#/components/schemas/ResourceIdentifier"}))
+	})
+})
@@ -0,0 +1,151 @@
+package api2go
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// readOnlySource only implements Finder and FindAll
+type readOnlySource struct {
+	source *fixtureSource
+}
+
+func (s readOnlySource) FindOne(ID string, req Request) (Responder, error) {
+	return s.source.FindOne(ID, req)
+}
+
+func (s readOnlySource) FindAll(req Request) (Responder, error) {
+	return s.source.FindAll(req)
+}
+
+// appendOnlySource only implements Creator
+type appendOnlySource struct {
+	source *fixtureSource
+}
+
+func (s appendOnlySource) Create(obj interface{}, req Request) (Responder, error) {
+	return s.source.Create(obj, req)
+}
+
+var _ = Describe("Supported operations", func() {
+	var (
+		api    *API
+		rec    *httptest.ResponseRecorder
+		source *fixtureSource
+	)
+
+	BeforeEach(func() {
+		source = &fixtureSource{posts: map[string]*Post{"1": {ID: "1", Title: "Hello, World!"}}}
+		api = NewAPI("v1")
+		rec = httptest.NewRecorder()
+	})
+
+	doRequest := func(method, url, body string) {
+		req, err := http.NewRequest(method, url, strings.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+	}
+
+	Context("read-only resources", func() {
+		BeforeEach(func() {
+			api.AddResource(Post{}, readOnlySource{source: source})
+		})
+
+		It("can be read", func() {
+			doRequest("GET", "/v1/posts/1", "")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+
+			rec = httptest.NewRecorder()
+			doRequest("GET", "/v1/posts/1/relationships/comments", "")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+		})
+
+		It("can not be updated", func() {
+			doRequest("PATCH", "/v1/posts/1", `{"data": {"type": "posts", "id": "1", "attributes": {"title": "New"}}}`)
+			Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+			Expect(rec.Header().Get("Allow")).To(Equal("GET,OPTIONS"))
+			Expect(rec.Body.Bytes()).To(MatchJSON(`{"errors": [{"status": "405", "title": "Method Not Allowed"}]}`))
+			Expect(source.posts["1"].Title).To(Equal("Hello, World!"))
+		})
+
+		It("can not be created", func() {
+			doRequest("POST", "/v1/posts", `{"data": {"type": "posts", "attributes": {"title": "New"}}}`)
+			Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+			Expect(rec.Header().Get("Allow")).To(Equal("GET,OPTIONS"))
+		})
+
+		It("can not change relationships", func() {
+			doRequest("PATCH", "/v1/posts/1/relationships/author", `{"data": null}`)
+			Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+			Expect(rec.Header().Get("Allow")).To(Equal("GET,OPTIONS"))
+		})
+
+		It("describes only the read operations in the OpenAPI document", func() {
+			paths := api.OpenAPI()["paths"].(map[string]interface{})
+			Expect(paths["/v1/posts"]).To(HaveKey("get"))
+			Expect(paths["/v1/posts"]).ToNot(HaveKey("post"))
+			Expect(paths["/v1/posts/{id}"]).ToNot(HaveKey("patch"))
+			Expect(paths["/v1/posts/{id}"]).ToNot(HaveKey("delete"))
+		})
+
+		It("rejects atomic updates", func() {
+			api.EnableAtomicOperations(nil)
+			req, err := http.NewRequest("POST", "/v1/operations", strings.NewReader(`{"atomic:operations": [
+				{"op": "remove", "ref": {"type": "posts", "id": "1"}}
+			]}`))
+			Expect(err).ToNot(HaveOccurred())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+			Expect(rec.Body.Bytes()).To(MatchJSON(`{"errors": [{
+				"status": "405",
+				"title": "Method Not Allowed",
+				"detail": "posts can not be deleted",
+				"source": {"pointer": "/atomic:operations/0"}
+			}]}`))
+			Expect(source.posts).To(HaveKey("1"))
+		})
+	})
+
+	Context("append-only resources", func() {
+		BeforeEach(func() {
+			api.AddResource(Post{}, appendOnlySource{source: source})
+		})
+
+		It("can be created", func() {
+			doRequest("POST", "/v1/posts", `{"data": {"type": "posts", "attributes": {"title": "New"}}}`)
+			Expect(rec.Code).To(Equal(http.StatusCreated))
+			Expect(source.posts).To(HaveLen(2))
+		})
+
+		It("can not be listed", func() {
+			doRequest("GET", "/v1/posts", "")
+			Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+			Expect(rec.Header().Get("Allow")).To(Equal("POST,OPTIONS"))
+		})
+
+		It("can not be read, updated or deleted", func() {
+			for _, method := range []string{"GET", "PATCH", "DELETE"} {
+				rec = httptest.NewRecorder()
+				doRequest(method, "/v1/posts/1", "")
+				Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+				Expect(rec.Header().Get("Allow")).To(Equal("OPTIONS"))
+				Expect(rec.Body.String()).To(ContainSubstring(`"status":"405"`))
+			}
+		})
+
+		It("still responds with 404 for unknown paths", func() {
+			doRequest("GET", "/v1/unknown/1", "")
+			Expect(rec.Code).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	It("panics for sources without any operation", func() {
+		Expect(func() {
+			api.AddResource(Post{}, struct{}{})
+		}).To(Panic())
+	})
+})
@@ -0,0 +1,199 @@
+package api2go
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type pageRecordingSource struct {
+	fixtureSource
+	queries []map[string][]string
+	pages   []Pagination
+}
+
+func (s *pageRecordingSource) PaginatedFindAll(req Request) (uint, Responder, error) {
+	s.queries = append(s.queries, req.QueryParams)
+	s.pages = append(s.pages, req.Pagination)
+	return s.fixtureSource.PaginatedFindAll(req)
+}
+
+var _ = Describe("Pagination options", func() {
+	var (
+		api    *API
+		rec    *httptest.ResponseRecorder
+		source *pageRecordingSource
+	)
+
+	BeforeEach(func() {
+		source = &pageRecordingSource{fixtureSource: fixtureSource{posts: map[string]*Post{
+			"1": {ID: "1", Title: "Hello, World!"},
+			"2": {ID: "2", Title: "I am NR. 2"},
+			"3": {ID: "3", Title: "I am NR. 3"},
+		}}}
+		api = NewAPI("v1")
+		rec = httptest.NewRecorder()
+	})
+
+	doRequest := func(url string) map[string]interface{} {
+		req, err := http.NewRequest("GET", url, nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+
+		var result map[string]interface{}
+		Expect(json.Unmarshal(rec.Body.Bytes(), &result)).To(Succeed())
+		return result
+	}
+
+	It("does not change requests without options", func() {
+		api.AddResource(Post{}, source)
+		doRequest("/v1/posts?page[limit]=1000000&page[offset]=0")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(source.queries[0]["page[limit]"]).To(Equal([]string{"1000000"}))
+	})
+
+	It("forces pagination with the default size", func() {
+		api.SetPaginationOptions(PaginationOptions{DefaultSize: 2, Force: true})
+		api.AddResource(Post{}, source)
+		result := doRequest("/v1/posts")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(source.queries).To(HaveLen(1))
+		Expect(source.queries[0]["page[number]"]).To(Equal([]string{"1"}))
+		Expect(source.queries[0]["page[size]"]).To(Equal([]string{"2"}))
+		Expect(result["links"]).To(HaveKeyWithValue("next", "/v1/posts?page[number]=2&page[size]=2"))
+	})
+
+	It("panics if pagination is forced without size", func() {
+		message := "pagination options with Force need a DefaultSize or MaxSize"
+		Expect(func() { api.SetPaginationOptions(PaginationOptions{Force: true}) }).To(PanicWith(message))
+		Expect(func() { WithPagination(PaginationOptions{Force: true, Clamp: true}) }).To(PanicWith(message))
+	})
+
+	It("adds the default size to requests without size", func() {
+		api.AddResource(Post{}, source, WithPagination(PaginationOptions{DefaultSize: 2}))
+		doRequest("/v1/posts?page[offset]=1")
+		Expect(source.queries[0]["page[limit]"]).To(Equal([]string{"2"}))
+	})
+
+	It("rejects page sizes above the maximum", func() {
+		api.SetPaginationOptions(PaginationOptions{MaxSize: 100})
+		api.AddResource(Post{}, source)
+		doRequest("/v1/posts?page[offset]=0&page[limit]=1000000")
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(rec.Body.Bytes()).To(MatchJSON(`{"errors": [{
+			"status": "400",
+			"title": "Invalid page size",
+			"detail": "page[limit] must not be greater than 100",
+			"source": {"parameter": "page[limit]"}
+		}]}`))
+	})
+
+	It("clamps page sizes above the maximum", func() {
+		api.SetPaginationOptions(PaginationOptions{MaxSize: 100, Clamp: true})
+		api.AddResource(Post{}, source)
+		doRequest("/v1/posts?page[number]=1&page[size]=1000")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(source.queries[0]["page[size]"]).To(Equal([]string{"100"}))
+	})
+
+	It("prefers the options of the resource", func() {
+		api.SetPaginationOptions(PaginationOptions{MaxSize: 1})
+		api.AddResource(Post{}, source, WithPagination(PaginationOptions{MaxSize: 10}))
+		doRequest("/v1/posts?page[number]=1&page[size]=5")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+	})
+
+	Context("parameters", func() {
+		BeforeEach(func() {
+			api.AddResource(Post{}, source)
+		})
+
+		It("passes the parsed parameters to the source", func() {
+			doRequest("/v1/posts?page[number]=2&page[size]=1")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(source.pages).To(Equal([]Pagination{{Number: 2, Size: 1}}))
+		})
+
+		It("rejects non-numeric parameters", func() {
+			doRequest("/v1/posts?page[number]=first&page[size]=1")
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(source.pages).To(BeEmpty())
+			Expect(rec.Body.Bytes()).To(MatchJSON(`{"errors": [{
+				"status": "400",
+				"title": "Invalid page parameter",
+				"detail": "page[number] must be a positive integer",
+				"source": {"parameter": "page[number]"}
+			}]}`))
+		})
+
+		It("rejects a page number of zero", func() {
+			doRequest("/v1/posts?page[number]=0&page[size]=1")
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(ContainSubstring(`"parameter":"page[number]"`))
+		})
+
+		It("rejects negative offsets", func() {
+			doRequest("/v1/posts?page[offset]=-1&page[limit]=1")
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(ContainSubstring(`"detail":"page[offset] must be a non-negative integer"`))
+		})
+
+		It("links to the last page from pages past it", func() {
+			result := doRequest("/v1/posts?page[number]=5&page[size]=2")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(result["links"]).To(Equal(map[string]interface{}{
+				"first": "/v1/posts?page[number]=1&page[size]=2",
+				"prev":  "/v1/posts?page[number]=2&page[size]=2",
+				"last":  "/v1/posts?page[number]=2&page[size]=2",
+			}))
+		})
+
+		It("links to the last offset from offsets past it", func() {
+			result := doRequest("/v1/posts?page[offset]=10&page[limit]=5")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(result["links"]).To(Equal(map[string]interface{}{
+				"first": "/v1/posts?page[limit]=5&page[offset]=0",
+				"prev":  "/v1/posts?page[limit]=5&page[offset]=0",
+				"last":  "/v1/posts?page[limit]=5&page[offset]=0",
+			}))
+		})
+	})
+
+	Context("meta", func() {
+		It("adds the total and the number of pages", func() {
+			api.AddResource(Post{}, source)
+			result := doRequest("/v1/posts?page[number]=1&page[size]=2")
+			Expect(result["meta"]).To(Equal(map[string]interface{}{"total": 3.0, "pages": 2.0}))
+		})
+
+		It("adds only the total for offset and limit", func() {
+			api.AddResource(Post{}, source)
+			result := doRequest("/v1/posts?page[offset]=0&page[limit]=2")
+			Expect(result["meta"]).To(Equal(map[string]interface{}{"total": 3.0}))
+		})
+
+		It("does not clobber the metadata of the responder", func() {
+			api.AddResource(Post{}, &metaSource{fixtureSource: source.fixtureSource})
+			result := doRequest("/v1/posts?page[number]=1&page[size]=2")
+			Expect(result["meta"]).To(Equal(map[string]interface{}{"total": "about 3", "pages": 2.0, "author": "me"}))
+		})
+
+		It("can be omitted", func() {
+			api.AddResource(Post{}, source, WithoutPaginationMeta())
+			result := doRequest("/v1/posts?page[number]=1&page[size]=2")
+			Expect(result).ToNot(HaveKey("meta"))
+		})
+	})
+})
+
+type metaSource struct {
+	fixtureSource
+}
+
+func (s *metaSource) PaginatedFindAll(req Request) (uint, Responder, error) {
+	count, response, err := s.fixtureSource.PaginatedFindAll(req)
+	return count, &Response{Res: response.Result(), Meta: map[string]interface{}{"total": "about 3", "author": "me"}}, err
+}
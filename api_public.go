@@ -1,6 +1,7 @@
 package api2go
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"strings"
@@ -13,10 +14,21 @@ import (
 type API struct {
 	router *httprouter.Router
 	// Route prefix, including slashes
-	prefix     string
-	info       information
-	resources  []resource
-	marshalers map[string]ContentMarshaler
+	prefix      string
+	info        information
+	resources   []resource
+	marshalers  map[string]ContentMarshaler
+	middlewares []Middleware
+	pagination  PaginationOptions
+	logger      Logger
+	// errorHandler converts errors into the HTTPError that is sent, AsHTTPError if nil
+	errorHandler ErrorHandler
+	// cors enables the handling of cross-origin requests if not nil
+	cors *CORSOptions
+	// optionsPaths contains all paths with an OPTIONS handler
+	optionsPaths map[string]bool
+	// mediaTypes contains the extensions and profiles and if the media type rules of JSON:API are enforced
+	mediaTypes mediaTypes
 }
 
 // Handler returns the http.Handler instance for the API.
@@ -30,11 +42,161 @@ func (api *API) Router() *httprouter.Router {
 }
 
 // AddResource registers a data source for the given resource
-// At least the CRUD interface must be implemented, all the other interfaces are optional.
+// The source must implement at least one of Finder, Creator, Updater, Deleter or FindAll, or their
+// context aware variants, and only the routes of the implemented operations are registered. All
+// the other interfaces are optional.
 // `resource` should be either an empty struct instance such as `Post{}` or a pointer to
 // a struct such as `&Post{}`. The same type will be used for constructing new elements.
-func (api *API) AddResource(prototype jsonapi.MarshalIdentifier, source CRUD) {
-	api.addResource(prototype, source, api.marshalers)
+// `options` can be used to configure the resource, e.g. with WithMiddleware.
+func (api *API) AddResource(prototype jsonapi.MarshalIdentifier, source interface{}, options ...ResourceOption) {
+	api.addResource(prototype, source, api.marshalers, options...)
+}
+
+// Use adds middlewares that are called for every request to a resource route, in the order
+// they were added and before the middlewares of the resource itself.
+func (api *API) Use(middlewares ...Middleware) {
+	api.middlewares = append(api.middlewares, middlewares...)
+}
+
+// SetPaginationOptions sets the pagination options of all resources that were not added with WithPagination.
+// It panics if Force is set without DefaultSize or MaxSize.
+func (api *API) SetPaginationOptions(options PaginationOptions) {
+	options.check()
+	api.pagination = options
+}
+
+// SetLogger sets the logger for the errors of all requests, which are logged with log.Println by default
+func (api *API) SetLogger(logger Logger) {
+	api.logger = logger
+}
+
+// SetErrorHandler sets the function that converts the errors of all requests into the HTTPError that is
+// sent to the client, e.g. to hide internal details or to add a request id to the ID of every Error.
+func (api *API) SetErrorHandler(handler ErrorHandler) {
+	api.errorHandler = handler
+}
+
+// Logger logs the errors of requests with the original error, the status code that was sent, the method,
+// the path and the resource route as key-value pairs. *slog.Logger implements it.
+type Logger interface {
+	Error(msg string, args ...interface{})
+}
+
+// ErrorHandler returns the HTTPError that is sent for err, which was returned by a resource or api2go
+// itself. route is the resource route that was hit and empty for requests that do not match one.
+// AsHTTPError returns the default. HTTPErrors without status, e.g. HTTPError{Errors: ...}, are sent
+// with the status of AsHTTPError(err), or 500 Internal Server Error if that has none either.
+type ErrorHandler func(err error, r *http.Request, route Route) HTTPError
+
+// PaginationOptions control the page sizes of index requests. Requests with page[number] but without
+// page[size], with page[offset] but without page[limit] or with a cursor but without page[size] get
+// the default page size. Sizes above the maximum are rejected with 400 Bad Request, or reduced to
+// the maximum if Clamp is set.
+type PaginationOptions struct {
+	// DefaultSize defaults to MaxSize
+	DefaultSize uint
+	// MaxSize is the maximum of page[size] and page[limit], 0 means unlimited
+	MaxSize uint
+	// Clamp reduces page sizes above MaxSize instead of rejecting them
+	Clamp bool
+	// Force requests the first page with the default size from PaginatedFindAll or CursorPaginatedFindAll
+	// for requests without page parameters instead of calling FindAll, it needs DefaultSize or MaxSize
+	Force bool
+}
+
+// check panics for options that can not be applied
+func (o PaginationOptions) check() {
+	if o.Force && o.DefaultSize == 0 && o.MaxSize == 0 {
+		panic("pagination options with Force need a DefaultSize or MaxSize")
+	}
+}
+
+// Middleware is called for every request to a resource route with the Route that was hit.
+// It must call next to continue handling the request, for example with a request that
+// carries an enriched context, or write a response on its own to stop.
+type Middleware func(w http.ResponseWriter, r *http.Request, route Route, next http.HandlerFunc)
+
+// Operation is the kind of resource route that was hit by a request
+type Operation string
+
+// All operations that can be passed to a Middleware
+const (
+	OperationIndex        Operation = "index"
+	OperationRead         Operation = "read"
+	OperationCreate       Operation = "create"
+	OperationUpdate       Operation = "update"
+	OperationDelete       Operation = "delete"
+	OperationRelationship Operation = "relationship"
+	OperationAtomic       Operation = "atomic"
+)
+
+// Route describes the resource route that was hit by a request. Relation is only set for
+// OperationRelationship, which covers the relationship routes as well as the related resource route.
+// Requests to the atomic operations endpoint run the middlewares of the API with OperationAtomic and
+// the middlewares of the resource of each operation with the route the operation would have on its own.
+type Route struct {
+	Resource  string
+	Operation Operation
+	Relation  string
+}
+
+// ResourceOption configures a resource in AddResource
+type ResourceOption func(*resource)
+
+// WithMiddleware adds middlewares that are only called for requests to the routes of this resource
+func WithMiddleware(middlewares ...Middleware) ResourceOption {
+	return func(res *resource) {
+		res.middlewares = append(res.middlewares, middlewares...)
+	}
+}
+
+// WithPagination sets the pagination options of the resource instead of the ones of the api.
+// It panics if Force is set without DefaultSize or MaxSize.
+func WithPagination(options PaginationOptions) ResourceOption {
+	options.check()
+	return func(res *resource) {
+		res.pagination = &options
+	}
+}
+
+// WithoutPaginationMeta omits the total count and the number of pages, which are otherwise added to
+// the meta of paginated documents, e.g. if the count returned by PaginatedFindAll is only an estimate
+func WithoutPaginationMeta() ResourceOption {
+	return func(res *resource) {
+		res.noPaginationMeta = true
+	}
+}
+
+// WithETags enables entity tags for the resource. Reads set the ETag header and respond with
+// 304 Not Modified to a matching If-None-Match header, updates and deletes fail with
+// 412 Precondition Failed if the If-Match header does not match the current object.
+// Models that implement Versioner use their version as entity tag, all others a hash of the
+// marshalled document. Every representation selected by include and fields has its own tag.
+func WithETags() ResourceOption {
+	return func(res *resource) {
+		res.etags = true
+	}
+}
+
+// ClientIDPolicy declares if clients may send the id of the resources they create
+type ClientIDPolicy int
+
+// All policies that can be passed to WithClientIDs
+const (
+	// ClientIDsAllowed passes an id sent by the client to SetID, without one the source assigns it
+	ClientIDsAllowed ClientIDPolicy = iota
+	// ClientIDsForbidden rejects create requests with an id with 403 Forbidden
+	ClientIDsForbidden
+	// ClientIDsRequired rejects create requests without an id with 422 Unprocessable Entity
+	ClientIDsRequired
+)
+
+// WithClientIDs sets the policy for ids generated by clients, which are allowed by default.
+// Sources can return ErrDuplicateID from Create to respond with 409 Conflict if the id is already taken.
+func WithClientIDs(policy ClientIDPolicy) ResourceOption {
+	return func(res *resource) {
+		res.clientIDs = policy
+	}
 }
 
 // Request contains additional information for FindOne and Find Requests
@@ -42,6 +204,95 @@ type Request struct {
 	PlainRequest *http.Request
 	QueryParams  map[string][]string
 	Header       http.Header
+	// Sort contains the parsed fields of the sort query parameter in the requested order
+	Sort []SortField
+	// Filter contains all parsed filter[...] query parameters, sorted by their parameter name
+	Filter []Filter
+	// Pagination contains the parsed page query parameters of PaginatedFindAll and CursorPaginatedFindAll
+	Pagination Pagination
+	// Extensions and Profiles contain the URIs of the ext and profile parameters of the JSON:API media type
+	// of the Content-Type header, or of the Accept header for requests without Content-Type
+	Extensions []string
+	Profiles   []string
+
+	ctx context.Context
+}
+
+// Context returns the context of the request. It is the context of PlainRequest unless it was
+// replaced with WithContext.
+func (r Request) Context() context.Context {
+	if r.ctx != nil {
+		return r.ctx
+	}
+
+	if r.PlainRequest != nil {
+		return r.PlainRequest.Context()
+	}
+
+	return context.Background()
+}
+
+// WithContext returns a copy of the request with its context changed to ctx, which can be
+// used to attach values or deadlines to a request
+func (r Request) WithContext(ctx context.Context) Request {
+	if ctx == nil {
+		panic("nil context")
+	}
+
+	r.ctx = ctx
+	return r
+}
+
+// SortField is one field of the sort query parameter, e.g. `-created` is sorted by
+// the field `created` in descending order
+type SortField struct {
+	Field      string
+	Descending bool
+}
+
+// FilterOperator is the comparison of a Filter
+type FilterOperator string
+
+// All filter operators that can be used in the filter query parameters
+const (
+	FilterEqual       FilterOperator = "eq"
+	FilterNotEqual    FilterOperator = "ne"
+	FilterLessThan    FilterOperator = "lt"
+	FilterGreaterThan FilterOperator = "gt"
+	FilterIn          FilterOperator = "in"
+	FilterLike        FilterOperator = "like"
+)
+
+// Filter is one filter query parameter. `filter[name]=foo` compares the field `name` with
+// FilterEqual, `filter[age][gt]=30` uses the given operator. Comma separated values are split
+// into Values. Parameter contains the name of the query parameter, e.g. `filter[age][gt]`.
+type Filter struct {
+	Field     string
+	Operator  FilterOperator
+	Values    []string
+	Parameter string
+}
+
+// Pagination contains the validated page query parameters, the fields of missing parameters
+// are zero. PaginatedFindAll is called with either Number and Size or Offset and Limit set,
+// CursorPaginatedFindAll with After or Before and Size.
+type Pagination struct {
+	Number uint
+	Size   uint
+	Offset uint
+	Limit  uint
+	After  string
+	Before string
+}
+
+// Cursors are returned by CursorPaginatedFindAll to generate the pagination links
+type Cursors struct {
+	// Prev is the cursor of the first resource of the page, which is used as page[before] of the
+	// prev link. There is no prev link if it is empty.
+	Prev string
+	// Next is the cursor of the last resource of the page, which is used as page[after] of the
+	// next link. There is no next link if it is empty.
+	Next string
 }
 
 //SetRedirectTrailingSlash enables 307 redirects on urls ending with /
@@ -74,17 +325,19 @@ func NewAPIWithMarshalers(prefix string, baseURL string, marshalers map[string]C
 		prefixSlashes = "/"
 	}
 
-	router := httprouter.New()
-	router.MethodNotAllowed = notAllowedHandler{marshalers: marshalers}
-
 	info := information{prefix: prefix, baseURL: baseURL}
 
-	return &API{
-		router:     router,
-		prefix:     prefixSlashes,
-		info:       info,
-		marshalers: marshalers,
+	api := &API{
+		router:       httprouter.New(),
+		prefix:       prefixSlashes,
+		info:         info,
+		marshalers:   marshalers,
+		optionsPaths: map[string]bool{},
 	}
+	api.router.MethodNotAllowed = notAllowedHandler{api: api}
+	api.router.NotFound = notFoundHandler{api: api}
+
+	return api
 }
 
 // NewAPI returns an initialized API instance
@@ -0,0 +1,73 @@
+package api2go
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type sortedSource struct {
+	fixtureSource
+	lastRequest Request
+}
+
+func (s *sortedSource) FindAll(req Request) (Responder, error) {
+	s.lastRequest = req
+	return s.fixtureSource.FindAll(req)
+}
+
+func (s *sortedSource) SortableFields() []string {
+	return []string{"title", "value"}
+}
+
+var _ = Describe("Sorting", func() {
+	var (
+		api    *API
+		rec    *httptest.ResponseRecorder
+		source *sortedSource
+	)
+
+	BeforeEach(func() {
+		source = &sortedSource{fixtureSource: fixtureSource{posts: map[string]*Post{
+			"1": {ID: "1", Title: "Hello, World!"},
+		}}}
+		api = NewAPI("v1")
+		api.AddResource(Post{}, source)
+		rec = httptest.NewRecorder()
+	})
+
+	It("parses the sort parameter into sort fields", func() {
+		req, err := http.NewRequest("GET", "/v1/posts?sort=-title,value", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(buildRequest(req).Sort).To(Equal([]SortField{
+			{Field: "title", Descending: true},
+			{Field: "value"},
+		}))
+	})
+
+	It("passes allowed sort fields to FindAll", func() {
+		req, err := http.NewRequest("GET", "/v1/posts?sort=value,-title", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(source.lastRequest.Sort).To(Equal([]SortField{
+			{Field: "value"},
+			{Field: "title", Descending: true},
+		}))
+	})
+
+	It("rejects sort fields that are not allowed", func() {
+		req, err := http.NewRequest("GET", "/v1/posts?sort=title,-created", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(rec.Body.Bytes()).To(MatchJSON(`{"errors":[{
+			"status": "400",
+			"title": "Invalid sort parameter",
+			"detail": "posts can not be sorted by created",
+			"source": {"parameter": "sort"}
+		}]}`))
+	})
+})
@@ -642,6 +642,113 @@ var _ = Describe("RestHandler", func() {
 			Expect(rec.Body.Bytes()).To(MatchJSON(expected))
 		})
 
+		It("GETs only the included relations requested by the include parameter", func() {
+			req, err := http.NewRequest("GET", "/v1/posts/1?include=comments", nil)
+			Expect(err).To(BeNil())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			expected, err := json.Marshal(map[string]interface{}{
+				"data":     post1Json,
+				"included": post1LinkedJSON[1:],
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rec.Body.Bytes()).To(MatchJSON(expected))
+		})
+
+		It("GETs collections with the requested included relations", func() {
+			req, err := http.NewRequest("GET", "/v1/posts?include=author", nil)
+			Expect(err).To(BeNil())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			expected, err := json.Marshal(map[string]interface{}{
+				"data":     []map[string]interface{}{post1Json, post2Json, post3Json},
+				"included": post1LinkedJSON[:1],
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rec.Body.Bytes()).To(MatchJSON(expected))
+		})
+
+		It("GETs no included relations with an empty include parameter", func() {
+			req, err := http.NewRequest("GET", "/v1/posts/1?include=", nil)
+			Expect(err).To(BeNil())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			expected, err := json.Marshal(map[string]interface{}{
+				"data": post1Json,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rec.Body.Bytes()).To(MatchJSON(expected))
+		})
+
+		It("returns 400 for unknown relations in the include parameter", func() {
+			req, err := http.NewRequest("GET", "/v1/posts/1?include=comments,unicorns", nil)
+			Expect(err).To(BeNil())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.Bytes()).To(MatchJSON(`{"errors":[{
+				"status": "400",
+				"title": "Invalid include parameter",
+				"detail": "posts has no relationship unicorns which could be included",
+				"source": {"parameter": "include"}
+			}]}`))
+		})
+
+		It("returns 400 for unknown nested relations in the include parameter", func() {
+			req, err := http.NewRequest("GET", "/v1/posts?include=author.posts", nil)
+			Expect(err).To(BeNil())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.Bytes()).To(MatchJSON(`{"errors":[{
+				"status": "400",
+				"title": "Invalid include parameter",
+				"detail": "users has no relationship posts which could be included",
+				"source": {"parameter": "include"}
+			}]}`))
+		})
+
+		It("GETs only the requested sparse fieldsets", func() {
+			req, err := http.NewRequest("GET", "/v1/posts/1?fields[posts]=title,author&fields[users]=", nil)
+			Expect(err).To(BeNil())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.Bytes()).To(MatchJSON(`{
+				"data": {
+					"id": "1",
+					"type": "posts",
+					"attributes": {
+						"title": "Hello, World!"
+					},
+					"relationships": {
+						"author": {
+							"data": {"id": "1", "type": "users"},
+							"links": {
+								"self": "/v1/posts/1/relationships/author",
+								"related": "/v1/posts/1/author"
+							}
+						}
+					}
+				},
+				"included": [
+					{"id": "1", "type": "users", "attributes": {}},
+					{"id": "1", "type": "comments", "attributes": {"value": "This is a stupid post!"}}
+				]
+			}`))
+		})
+
+		It("GETs paginated collections with sparse fieldsets", func() {
+			req, err := http.NewRequest("GET", "/v1/posts?page[number]=1&page[size]=10&fields[posts]=value&include=", nil)
+			Expect(err).To(BeNil())
+			api.Handler().ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			var result map[string]interface{}
+			Expect(json.Unmarshal(rec.Body.Bytes(), &result)).To(BeNil())
+			Expect(result["data"]).To(HaveLen(3))
+			for _, post := range result["data"].([]interface{}) {
+				Expect(post).To(HaveKeyWithValue("attributes", map[string]interface{}{"value": nil}))
+				Expect(post).ToNot(HaveKey("relationships"))
+			}
+		})
+
 		It("GETs related struct from resource url", func() {
 			req, err := http.NewRequest("GET", "/v1/posts/1/author", nil)
 			Expect(err).ToNot(HaveOccurred())
@@ -780,7 +887,7 @@ var _ = Describe("RestHandler", func() {
 			req, err := http.NewRequest("POST", "/v1/posts", reqBody)
 			Expect(err).To(BeNil())
 			api.Handler().ServeHTTP(rec, req)
-			Expect(rec.Code).To(Equal(http.StatusInternalServerError))
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
 			Expect(rec.Header().Get("Location")).To(Equal(""))
 			Expect(rec.Body.Bytes()).ToNot(HaveLen(0))
 		})
@@ -789,7 +896,7 @@ var _ = Describe("RestHandler", func() {
 			api.Handler().ServeHTTP(rec, req)
 			Expect(err).To(BeNil())
 			Expect(rec.Code).To(Equal(http.StatusNoContent))
-			Expect(rec.Header().Get("Allow")).To(Equal("GET,POST,PATCH,OPTIONS"))
+			Expect(rec.Header().Get("Allow")).To(Equal("GET,POST,OPTIONS"))
 		})
 
 		It("OPTIONS on element route", func() {
@@ -813,8 +920,11 @@ var _ = Describe("RestHandler", func() {
 			req, err := http.NewRequest("PATCH", "/v1/posts/1", reqBody)
 			Expect(err).To(BeNil())
 			api.Handler().ServeHTTP(rec, req)
-			Expect(rec.Code).To(Equal(http.StatusForbidden))
-			Expect(string(rec.Body.Bytes())).To(MatchJSON(`{"errors":[{"status":"403","title":"missing mandatory type key."}]}`))
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(string(rec.Body.Bytes())).To(MatchJSON(`{"errors":[
+				{"status":"400","title":"Invalid document","detail":"title is not a valid member of a resource object","source":{"pointer":"/data/title"}},
+				{"status":"400","title":"Invalid document","detail":"type is missing","source":{"pointer":"/data/type"}}
+			]}`))
 		})
 
 		It("patch must contain type and id but does not have id", func() {
@@ -822,8 +932,11 @@ var _ = Describe("RestHandler", func() {
 			req, err := http.NewRequest("PATCH", "/v1/posts/1", reqBody)
 			Expect(err).To(BeNil())
 			api.Handler().ServeHTTP(rec, req)
-			Expect(rec.Code).To(Equal(http.StatusForbidden))
-			Expect(string(rec.Body.Bytes())).To(MatchJSON(`{"errors":[{"status":"403","title":"missing mandatory id key."}]}`))
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(string(rec.Body.Bytes())).To(MatchJSON(`{"errors":[
+				{"status":"400","title":"Invalid document","detail":"title is not a valid member of a resource object","source":{"pointer":"/data/title"}},
+				{"status":"400","title":"Invalid document","detail":"id is missing","source":{"pointer":"/data/id"}}
+			]}`))
 		})
 
 		Context("Updating", func() {
@@ -976,8 +1089,7 @@ var _ = Describe("RestHandler", func() {
 
 	Context("marshal errors correctly", func() {
 		var (
-			source    *fixtureSource
-			post1Json map[string]interface{}
+			source *fixtureSource
 
 			api *API
 			rec *httptest.ResponseRecorder
@@ -988,12 +1100,6 @@ var _ = Describe("RestHandler", func() {
 				"1": {ID: "1", Title: "Hello, World!"},
 			}, false}
 
-			post1Json = map[string]interface{}{
-				"id":    "1",
-				"title": "Hello, World!",
-				"value": nil,
-			}
-
 			api = NewAPI("")
 			api.AddResource(Post{}, source)
 
@@ -1001,7 +1107,7 @@ var _ = Describe("RestHandler", func() {
 		})
 
 		It("POSTSs new objects", func() {
-			reqBody := strings.NewReader(`{"data": [{"title": "", "type": "posts"}]}`)
+			reqBody := strings.NewReader(`{"data": [{"type": "posts", "attributes": {"title": ""}}]}`)
 			req, err := http.NewRequest("POST", "/posts", reqBody)
 			Expect(err).To(BeNil())
 			api.Handler().ServeHTTP(rec, req)
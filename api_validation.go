@@ -0,0 +1,169 @@
+package api2go
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/manyminds/api2go/jsonapi"
+)
+
+// validate checks an unmarshalled object with the struct tags and the Validator of its model and the
+// ObjectValidator of the source. All errors are returned together as 422 Unprocessable Entity.
+func (res *resource) validate(obj interface{}, req Request) error {
+	errs := res.validateAttributes(obj)
+
+	if validator, ok := obj.(Validator); ok {
+		errs = append(errs, validator.Validate()...)
+	} else if validator, ok := getPointerToStruct(obj).(Validator); ok {
+		errs = append(errs, validator.Validate()...)
+	}
+
+	if validator, ok := res.source.(ObjectValidator); ok {
+		errs = append(errs, validator.ValidateObject(obj, req)...)
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	for i := range errs {
+		if errs[i].Status == "" {
+			errs[i].Status = strconv.Itoa(http.StatusUnprocessableEntity)
+		}
+	}
+
+	httpErr := NewHTTPError(nil, "Validation failed", http.StatusUnprocessableEntity)
+	httpErr.Errors = errs
+
+	return httpErr
+}
+
+// attributeValidation contains the validation settings of the jsonapi struct tag of an attribute
+type attributeValidation struct {
+	name     string
+	index    []int
+	required bool
+	// maxLen is -1 for attributes without maxlen
+	maxLen int
+}
+
+// parseValidations returns the validation settings of the jsonapi struct tags of all attributes of
+// prototype, sorted by attribute name to report errors in a stable order:
+//
+//	required   the attribute must not have the zero value of its type
+//	maxlen=40  strings must not have more than 40 characters, slices and maps not more than 40 elements
+//
+// It panics for invalid settings, so that they are found when the resource is added.
+func parseValidations(prototype jsonapi.MarshalIdentifier) []attributeValidation {
+	fields := jsonapi.AttributeFields(prototype)
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var validations []attributeValidation
+	for _, name := range names {
+		field := fields[name]
+		validation := attributeValidation{
+			name:     name,
+			index:    field.Index,
+			required: jsonapi.GetTagValueByName(field, "required") != "",
+			maxLen:   -1,
+		}
+
+		if setting := jsonapi.GetTagValueByName(field, "maxlen"); setting != "" {
+			maxLen, err := strconv.Atoi(strings.TrimSpace(setting))
+			if err != nil || maxLen < 0 {
+				panic(fmt.Sprintf("invalid maxlen %q of field %s", setting, field.Name))
+			}
+
+			if !hasLength(field.Type) {
+				panic(fmt.Sprintf("maxlen of field %s needs a string, slice, array or map", field.Name))
+			}
+
+			validation.maxLen = maxLen
+		}
+
+		if validation.required || validation.maxLen >= 0 {
+			validations = append(validations, validation)
+		}
+	}
+
+	return validations
+}
+
+// validateAttributes checks the attributes of obj against the validation settings of the resource
+func (res *resource) validateAttributes(obj interface{}) []Error {
+	val := reflect.ValueOf(obj)
+	if val.Kind() == reflect.Ptr {
+		val = val.Elem()
+	}
+
+	var errs []Error
+	for _, validation := range res.validations {
+		value := val.FieldByIndex(validation.index)
+		name := validation.name
+
+		if validation.required && isZero(value) {
+			errs = append(errs, newValidationError(name, fmt.Sprintf("%s is required", name)))
+			continue
+		}
+
+		if length, unit := valueLength(value); validation.maxLen >= 0 && length > validation.maxLen {
+			errs = append(errs, newValidationError(name, fmt.Sprintf("%s must not have more than %d %s", name, validation.maxLen, unit)))
+		}
+	}
+
+	return errs
+}
+
+func newValidationError(attribute, detail string) Error {
+	return Error{
+		Status: strconv.Itoa(http.StatusUnprocessableEntity),
+		Title:  "Invalid attribute",
+		Detail: detail,
+		Source: &ErrorSource{Pointer: "/data/attributes/" + attribute},
+	}
+}
+
+func isZero(value reflect.Value) bool {
+	return reflect.DeepEqual(value.Interface(), reflect.Zero(value.Type()).Interface())
+}
+
+func hasLength(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return true
+	default:
+		return false
+	}
+}
+
+// valueLength returns the number of characters of strings and the number of elements of other values
+func valueLength(value reflect.Value) (int, string) {
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return 0, ""
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		return utf8.RuneCountInString(value.String()), "characters"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return value.Len(), "elements"
+	default:
+		return 0, ""
+	}
+}
@@ -0,0 +1,202 @@
+package api2go
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type Account struct {
+	ID       string   `json:"-"`
+	UserName string   `jsonapi:"name=user-name;required;maxlen=10"`
+	Email    string   `json:"email"`
+	Roles    []string `jsonapi:"maxlen=2"`
+}
+
+func (a Account) GetID() string {
+	return a.ID
+}
+
+func (a *Account) SetID(ID string) error {
+	a.ID = ID
+	return nil
+}
+
+func (a *Account) Validate() []Error {
+	if a.Email != "" && !strings.Contains(a.Email, "@") {
+		return []Error{{Title: "Invalid email", Source: &ErrorSource{Pointer: "/data/attributes/email"}}}
+	}
+
+	return nil
+}
+
+// invalidAccount has a maxlen setting that is not a number
+type invalidAccount struct {
+	ID   string `json:"-"`
+	Name string `jsonapi:"maxlen=ten"`
+}
+
+func (a invalidAccount) GetID() string {
+	return a.ID
+}
+
+// countedAccount has a maxlen setting on an attribute without length
+type countedAccount struct {
+	ID    string `json:"-"`
+	Count int    `jsonapi:"maxlen=10"`
+}
+
+func (a countedAccount) GetID() string {
+	return a.ID
+}
+
+type accountSource struct {
+	accounts map[string]*Account
+}
+
+func (s *accountSource) FindOne(ID string, req Request) (Responder, error) {
+	if account, ok := s.accounts[ID]; ok {
+		return &Response{Res: *account}, nil
+	}
+
+	return &Response{}, NewHTTPError(nil, "account not found", http.StatusNotFound)
+}
+
+func (s *accountSource) Create(obj interface{}, req Request) (Responder, error) {
+	account := obj.(Account)
+	account.ID = "new"
+	s.accounts[account.ID] = &account
+	return &Response{Res: account, Code: http.StatusCreated}, nil
+}
+
+func (s *accountSource) Delete(ID string, req Request) (Responder, error) {
+	delete(s.accounts, ID)
+	return &Response{Code: http.StatusNoContent}, nil
+}
+
+func (s *accountSource) Update(obj interface{}, req Request) (Responder, error) {
+	account := obj.(Account)
+	s.accounts[account.ID] = &account
+	return &Response{Code: http.StatusNoContent}, nil
+}
+
+func (s *accountSource) ValidateObject(obj interface{}, req Request) []Error {
+	if obj.(Account).UserName == "admin" {
+		return []Error{{Status: "409", Title: "Taken", Source: &ErrorSource{Pointer: "/data/attributes/user-name"}}}
+	}
+
+	return nil
+}
+
+type bulkAccountSource struct {
+	accountSource
+	created []interface{}
+}
+
+func (s *bulkAccountSource) BulkCreate(objs []interface{}, req Request) (Responder, error) {
+	s.created = objs
+	return &Response{Code: http.StatusNoContent}, nil
+}
+
+var _ = Describe("Validation", func() {
+	var (
+		api    *API
+		rec    *httptest.ResponseRecorder
+		source *accountSource
+	)
+
+	BeforeEach(func() {
+		source = &accountSource{accounts: map[string]*Account{
+			"1": {ID: "1", UserName: "marvin"},
+		}}
+		api = NewAPI("v1")
+		api.AddResource(Account{}, source)
+		rec = httptest.NewRecorder()
+	})
+
+	doRequest := func(method, url, body string) {
+		req, err := http.NewRequest(method, url, strings.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+	}
+
+	It("creates valid objects", func() {
+		doRequest("POST", "/v1/accounts", `{"data": {"type": "accounts", "attributes": {"user-name": "arthur", "email": "arthur@earth"}}}`)
+		Expect(rec.Code).To(Equal(http.StatusCreated))
+		Expect(source.accounts).To(HaveKey("new"))
+	})
+
+	It("checks the struct tags", func() {
+		doRequest("POST", "/v1/accounts", `{"data": {"type": "accounts", "attributes": {"roles": ["a", "b", "c"]}}}`)
+		Expect(rec.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(source.accounts).ToNot(HaveKey("new"))
+		Expect(rec.Body.Bytes()).To(MatchJSON(`{"errors": [
+			{
+				"status": "422",
+				"title": "Invalid attribute",
+				"detail": "roles must not have more than 2 elements",
+				"source": {"pointer": "/data/attributes/roles"}
+			},
+			{
+				"status": "422",
+				"title": "Invalid attribute",
+				"detail": "user-name is required",
+				"source": {"pointer": "/data/attributes/user-name"}
+			}
+		]}`))
+	})
+
+	It("counts characters for the maximum length", func() {
+		doRequest("POST", "/v1/accounts", `{"data": {"type": "accounts", "attributes": {"user-name": "zaphodbeeb"}}}`)
+		Expect(rec.Code).To(Equal(http.StatusCreated))
+
+		rec = httptest.NewRecorder()
+		doRequest("POST", "/v1/accounts", `{"data": {"type": "accounts", "attributes": {"user-name": "zaphodbeebl"}}}`)
+		Expect(rec.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(rec.Body.String()).To(ContainSubstring("user-name must not have more than 10 characters"))
+	})
+
+	It("calls the validator of the model", func() {
+		doRequest("PATCH", "/v1/accounts/1", `{"data": {"type": "accounts", "id": "1", "attributes": {"email": "marvin"}}}`)
+		Expect(rec.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(source.accounts["1"].Email).To(BeEmpty())
+		Expect(rec.Body.Bytes()).To(MatchJSON(`{"errors": [
+			{"status": "422", "title": "Invalid email", "source": {"pointer": "/data/attributes/email"}}
+		]}`))
+	})
+
+	It("calls the validator of the source", func() {
+		doRequest("PATCH", "/v1/accounts/1", `{"data": {"type": "accounts", "id": "1", "attributes": {"user-name": "admin"}}}`)
+		Expect(rec.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(rec.Body.Bytes()).To(MatchJSON(`{"errors": [
+			{"status": "409", "title": "Taken", "source": {"pointer": "/data/attributes/user-name"}}
+		]}`))
+	})
+
+	It("validates the updated object as a whole", func() {
+		doRequest("PATCH", "/v1/accounts/1", `{"data": {"type": "accounts", "id": "1", "attributes": {"email": "marvin@heart-of-gold"}}}`)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(source.accounts["1"].UserName).To(Equal("marvin"))
+	})
+
+	It("panics for invalid validation settings when the resource is added", func() {
+		Expect(func() { api.AddResource(invalidAccount{}, source) }).To(PanicWith(`invalid maxlen "ten" of field Name`))
+		Expect(func() { api.AddResource(countedAccount{}, source) }).To(PanicWith("maxlen of field Count needs a string, slice, array or map"))
+	})
+
+	It("points to the invalid objects of bulk requests", func() {
+		bulkSource := &bulkAccountSource{}
+		api = NewAPI("v1")
+		api.AddResource(Account{}, bulkSource)
+		doRequest("POST", "/v1/accounts", `{"data": [
+			{"type": "accounts", "attributes": {"user-name": "arthur"}},
+			{"type": "accounts", "attributes": {"email": "ford@betelgeuse"}}
+		]}`)
+		Expect(rec.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(bulkSource.created).To(BeEmpty())
+		Expect(rec.Body.String()).To(ContainSubstring(`"pointer":"/data/1/attributes/user-name"`))
+	})
+})
@@ -0,0 +1,382 @@
+// Package client consumes JSONAPI.org servers built with api2go.
+//
+// Resources are the same structs that are registered with api2go.API. They must implement
+// jsonapi.MarshalIdentifier and, to be unmarshalled from responses, jsonapi.UnmarshalIdentifier.
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"reflect"
+	"strings"
+
+	"github.com/manyminds/api2go"
+	"github.com/manyminds/api2go/jsonapi"
+)
+
+const contentType = "application/vnd.api+json"
+
+// Client talks to the resources of an api2go server
+type Client struct {
+	// HTTPClient is used for all requests, http.DefaultClient if nil
+	HTTPClient *http.Client
+	baseURL    *url.URL
+	prefix     string
+}
+
+// New returns a client for the server at baseURL whose resources are registered with prefix,
+// like the arguments of api2go.NewAPIWithBaseURL.
+func New(baseURL, prefix string) (*Client, error) {
+	base, err := url.Parse(strings.TrimRight(baseURL, "/"))
+	if err != nil {
+		return nil, err
+	}
+
+	prefix = strings.Trim(prefix, "/")
+	if prefix != "" {
+		prefix = "/" + prefix
+	}
+
+	return &Client{baseURL: base, prefix: prefix + "/"}, nil
+}
+
+// Get fetches the resource with the given id into target, which must be a pointer to a struct
+func (c *Client) Get(id string, target interface{}) error {
+	name, err := resourceName(target)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.do("GET", c.resourceURL(name, id), nil, target)
+	return err
+}
+
+// List fetches all resources into target, which must be a pointer to a slice of structs. The optional
+// query is added to the first request, all further pages are fetched by following `links.next`.
+func (c *Client) List(target interface{}, query url.Values) error {
+	targetVal := reflect.ValueOf(target)
+	if targetVal.Kind() != reflect.Ptr || targetVal.Elem().Kind() != reflect.Slice {
+		return errors.New("target must be a pointer to a slice")
+	}
+
+	name, err := resourceName(target)
+	if err != nil {
+		return err
+	}
+
+	next := c.resourceURL(name, "")
+	if len(query) > 0 {
+		next += "?" + query.Encode()
+	}
+
+	result := reflect.MakeSlice(targetVal.Elem().Type(), 0, 0)
+	for next != "" {
+		page := reflect.New(targetVal.Elem().Type())
+		doc, err := c.do("GET", next, nil, page.Interface())
+		if err != nil {
+			return err
+		}
+
+		result = reflect.AppendSlice(result, page.Elem())
+
+		next = ""
+		if links, ok := doc["links"].(map[string]interface{}); ok {
+			if link, ok := links["next"].(string); ok && link != "" {
+				next, err = c.resolve(link)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	targetVal.Elem().Set(result)
+	return nil
+}
+
+// Create posts obj to its resource. If obj is a pointer, the created resource is unmarshalled
+// into it, or only the ID from the Location header if the server did not return the resource.
+func (c *Client) Create(obj jsonapi.MarshalIdentifier) error {
+	name, err := resourceName(obj)
+	if err != nil {
+		return err
+	}
+
+	body, err := jsonapi.Marshal(obj)
+	if err != nil {
+		return err
+	}
+
+	target, _ := obj.(jsonapi.UnmarshalIdentifier)
+	resp, err := c.request("POST", c.resourceURL(name, ""), body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		if location := resp.Header.Get("Location"); location != "" && target != nil {
+			return target.SetID(path.Base(location))
+		}
+
+		return nil
+	}
+
+	_, err = decode(resp, target)
+	return err
+}
+
+// Update patches the resource of obj with all its fields. If obj is a pointer and the server
+// returns the updated resource, it is unmarshalled into obj.
+func (c *Client) Update(obj jsonapi.MarshalIdentifier) error {
+	name, err := resourceName(obj)
+	if err != nil {
+		return err
+	}
+
+	body, err := jsonapi.Marshal(obj)
+	if err != nil {
+		return err
+	}
+
+	target, _ := obj.(jsonapi.UnmarshalIdentifier)
+	_, err = c.do("PATCH", c.resourceURL(name, obj.GetID()), body, target)
+	return err
+}
+
+// Delete deletes the resource of obj
+func (c *Client) Delete(obj jsonapi.MarshalIdentifier) error {
+	name, err := resourceName(obj)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.do("DELETE", c.resourceURL(name, obj.GetID()), nil, nil)
+	return err
+}
+
+// GetRelated fetches the resources related to obj by relation into target, which must be a pointer
+// to a struct for to-one and a pointer to a slice for to-many relations
+func (c *Client) GetRelated(obj jsonapi.MarshalIdentifier, relation string, target interface{}) error {
+	name, err := resourceName(obj)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.do("GET", c.resourceURL(name, obj.GetID())+"/"+relation, nil, target)
+	return err
+}
+
+// ReplaceToOne sets the to-one relation of obj to the resource with the given id, an empty id
+// removes the relation
+func (c *Client) ReplaceToOne(obj jsonapi.MarshalIdentifier, relation, id string) error {
+	relationType, err := referenceType(obj, relation)
+	if err != nil {
+		return err
+	}
+
+	var data interface{}
+	if id != "" {
+		data = map[string]interface{}{"type": relationType, "id": id}
+	}
+
+	return c.editRelationship("PATCH", obj, relation, data)
+}
+
+// ReplaceToMany replaces all resources of the to-many relation of obj with the given ids
+func (c *Client) ReplaceToMany(obj jsonapi.MarshalIdentifier, relation string, ids []string) error {
+	return c.editToMany("PATCH", obj, relation, ids)
+}
+
+// AddToMany adds the given ids to the to-many relation of obj
+func (c *Client) AddToMany(obj jsonapi.MarshalIdentifier, relation string, ids []string) error {
+	return c.editToMany("POST", obj, relation, ids)
+}
+
+// DeleteToMany removes the given ids from the to-many relation of obj
+func (c *Client) DeleteToMany(obj jsonapi.MarshalIdentifier, relation string, ids []string) error {
+	return c.editToMany("DELETE", obj, relation, ids)
+}
+
+func (c *Client) editToMany(method string, obj jsonapi.MarshalIdentifier, relation string, ids []string) error {
+	relationType, err := referenceType(obj, relation)
+	if err != nil {
+		return err
+	}
+
+	data := []interface{}{}
+	for _, id := range ids {
+		data = append(data, map[string]interface{}{"type": relationType, "id": id})
+	}
+
+	return c.editRelationship(method, obj, relation, data)
+}
+
+func (c *Client) editRelationship(method string, obj jsonapi.MarshalIdentifier, relation string, data interface{}) error {
+	name, err := resourceName(obj)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.do(method, c.resourceURL(name, obj.GetID())+"/relationships/"+relation, map[string]interface{}{"data": data}, nil)
+	return err
+}
+
+func (c *Client) resourceURL(name, id string) string {
+	result := c.baseURL.String() + c.prefix + name
+	if id != "" {
+		result += "/" + id
+	}
+
+	return result
+}
+
+// resolve returns the absolute url of a link, which the server generates without
+// host unless it has a base url
+func (c *Client) resolve(link string) (string, error) {
+	linkURL, err := url.Parse(link)
+	if err != nil {
+		return "", err
+	}
+
+	return c.baseURL.ResolveReference(linkURL).String(), nil
+}
+
+// do sends a request and unmarshals the data of the response into target, if target is not nil
+// and the response has a body. The complete response document is returned.
+func (c *Client) do(method, location string, body map[string]interface{}, target interface{}) (map[string]interface{}, error) {
+	resp, err := c.request(method, location, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return decode(resp, target)
+}
+
+func (c *Client) request(method, location string, body map[string]interface{}) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		content, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+
+		reader = bytes.NewReader(content)
+	}
+
+	req, err := http.NewRequest(method, location, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", contentType)
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		return nil, decodeError(resp)
+	}
+
+	return resp, nil
+}
+
+func decode(resp *http.Response, target interface{}) (map[string]interface{}, error) {
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(content) == 0 {
+		return map[string]interface{}{}, nil
+	}
+
+	doc := map[string]interface{}{}
+	err = json.Unmarshal(content, &doc)
+	if err != nil {
+		return nil, err
+	}
+
+	if target == nil || reflect.ValueOf(target).Kind() != reflect.Ptr || doc["data"] == nil {
+		return doc, nil
+	}
+
+	return doc, jsonapi.Unmarshal(doc, target)
+}
+
+// decodeError converts the errors array of an error response into an api2go.HTTPError
+func decodeError(resp *http.Response) error {
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var doc struct {
+		Errors []api2go.Error `json:"errors"`
+	}
+	// error responses of other servers or proxies may not be json at all
+	_ = json.Unmarshal(content, &doc)
+
+	msg := http.StatusText(resp.StatusCode)
+	if len(doc.Errors) > 0 && doc.Errors[0].Title != "" {
+		msg = doc.Errors[0].Title
+	}
+
+	httpErr := api2go.NewHTTPError(fmt.Errorf("%s %s", resp.Request.Method, resp.Request.URL), msg, resp.StatusCode)
+	httpErr.Errors = doc.Errors
+
+	return httpErr
+}
+
+// resourceName returns the name of the resource of a struct, a pointer to it or a slice of them
+// in the same way api2go.API.AddResource does
+func resourceName(obj interface{}) (string, error) {
+	objType := reflect.TypeOf(obj)
+	for objType != nil && (objType.Kind() == reflect.Ptr || objType.Kind() == reflect.Slice) {
+		objType = objType.Elem()
+	}
+
+	if objType == nil || objType.Kind() != reflect.Struct {
+		return "", errors.New("resource must be a struct, a pointer to a struct or a slice of them")
+	}
+
+	if namer, ok := reflect.Zero(objType).Interface().(jsonapi.EntityNamer); ok {
+		return namer.GetName(), nil
+	}
+	if namer, ok := reflect.New(objType).Interface().(jsonapi.EntityNamer); ok {
+		return namer.GetName(), nil
+	}
+
+	return jsonapi.Jsonify(jsonapi.Pluralize(objType.Name())), nil
+}
+
+func referenceType(obj jsonapi.MarshalIdentifier, relation string) (string, error) {
+	if references, ok := obj.(jsonapi.MarshalReferences); ok {
+		for _, reference := range references.GetReferences() {
+			if reference.Name == relation {
+				return reference.Type, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("%T has no relation %s", obj, relation)
+}
@@ -0,0 +1,16 @@
+package client
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"io/ioutil"
+	"log"
+
+	"testing"
+)
+
+func TestClient(t *testing.T) {
+	RegisterFailHandler(Fail)
+	log.SetOutput(ioutil.Discard)
+	RunSpecs(t, "Client Suite")
+}
@@ -0,0 +1,291 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+
+	"github.com/manyminds/api2go"
+	"github.com/manyminds/api2go/jsonapi"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type Post struct {
+	ID         string   `json:"-"`
+	Title      string   `json:"title"`
+	AuthorID   string   `json:"-"`
+	CommentIDs []string `json:"-"`
+}
+
+func (p Post) GetID() string {
+	return p.ID
+}
+
+func (p *Post) SetID(ID string) error {
+	p.ID = ID
+	return nil
+}
+
+func (p Post) GetReferences() []jsonapi.Reference {
+	return []jsonapi.Reference{
+		{Name: "author", Type: "users"},
+		{Name: "comments", Type: "comments"},
+	}
+}
+
+func (p Post) GetReferencedIDs() []jsonapi.ReferenceID {
+	result := []jsonapi.ReferenceID{}
+	if p.AuthorID != "" {
+		result = append(result, jsonapi.ReferenceID{ID: p.AuthorID, Name: "author", Type: "users"})
+	}
+	for _, id := range p.CommentIDs {
+		result = append(result, jsonapi.ReferenceID{ID: id, Name: "comments", Type: "comments"})
+	}
+
+	return result
+}
+
+func (p *Post) SetToOneReferenceID(name, ID string) error {
+	if name == "author" {
+		p.AuthorID = ID
+		return nil
+	}
+
+	return errors.New("There is no to-one relationship with the name " + name)
+}
+
+func (p *Post) SetToManyReferenceIDs(name string, IDs []string) error {
+	if name == "comments" {
+		p.CommentIDs = IDs
+		return nil
+	}
+
+	return errors.New("There is no to-many relationship with the name " + name)
+}
+
+func (p *Post) AddToManyIDs(name string, IDs []string) error {
+	p.CommentIDs = append(p.CommentIDs, IDs...)
+	return nil
+}
+
+func (p *Post) DeleteToManyIDs(name string, IDs []string) error {
+	kept := []string{}
+	for _, id := range p.CommentIDs {
+		obsolete := false
+		for _, obsoleteID := range IDs {
+			obsolete = obsolete || id == obsoleteID
+		}
+		if !obsolete {
+			kept = append(kept, id)
+		}
+	}
+	p.CommentIDs = kept
+	return nil
+}
+
+type Response struct {
+	Res  interface{}
+	Code int
+}
+
+func (r Response) Metadata() map[string]interface{} {
+	return map[string]interface{}{}
+}
+
+func (r Response) Result() interface{} {
+	return r.Res
+}
+
+func (r Response) StatusCode() int {
+	return r.Code
+}
+
+type postSource struct {
+	posts map[string]Post
+}
+
+func (s *postSource) sorted() []Post {
+	ids := []string{}
+	for id := range s.posts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	result := []Post{}
+	for _, id := range ids {
+		result = append(result, s.posts[id])
+	}
+	return result
+}
+
+func (s *postSource) FindAll(req api2go.Request) (api2go.Responder, error) {
+	return &Response{Res: s.sorted()}, nil
+}
+
+func (s *postSource) PaginatedFindAll(req api2go.Request) (uint, api2go.Responder, error) {
+	posts := s.sorted()
+	number, _ := strconv.Atoi(req.QueryParams["page[number]"][0])
+	size, _ := strconv.Atoi(req.QueryParams["page[size]"][0])
+	start := (number - 1) * size
+	end := start + size
+	if end > len(posts) {
+		end = len(posts)
+	}
+
+	return uint(len(posts)), &Response{Res: posts[start:end]}, nil
+}
+
+func (s *postSource) FindOne(ID string, req api2go.Request) (api2go.Responder, error) {
+	post, ok := s.posts[ID]
+	if !ok {
+		return nil, api2go.NewHTTPError(nil, "post not found", http.StatusNotFound)
+	}
+
+	return &Response{Res: post}, nil
+}
+
+func (s *postSource) Create(obj interface{}, req api2go.Request) (api2go.Responder, error) {
+	post := obj.(Post)
+	if post.Title == "" {
+		httpErr := api2go.NewHTTPError(nil, "invalid post", http.StatusUnprocessableEntity)
+		httpErr.Errors = []api2go.Error{{
+			Status: "422",
+			Title:  "title must not be empty",
+			Source: &api2go.ErrorSource{Pointer: "/data/attributes/title"},
+		}}
+		return nil, httpErr
+	}
+
+	post.ID = strconv.Itoa(len(s.posts) + 1)
+	s.posts[post.ID] = post
+
+	if post.Title == "silent" {
+		return &Response{Res: post, Code: http.StatusNoContent}, nil
+	}
+
+	return &Response{Res: post, Code: http.StatusCreated}, nil
+}
+
+func (s *postSource) Delete(id string, req api2go.Request) (api2go.Responder, error) {
+	delete(s.posts, id)
+	return &Response{Code: http.StatusNoContent}, nil
+}
+
+func (s *postSource) Update(obj interface{}, req api2go.Request) (api2go.Responder, error) {
+	post := obj.(Post)
+	s.posts[post.ID] = post
+	return &Response{Res: post, Code: http.StatusOK}, nil
+}
+
+var _ = Describe("Client", func() {
+	var (
+		source *postSource
+		server *httptest.Server
+		client *Client
+	)
+
+	BeforeEach(func() {
+		source = &postSource{posts: map[string]Post{
+			"1": {ID: "1", Title: "First", AuthorID: "1", CommentIDs: []string{"1"}},
+			"2": {ID: "2", Title: "Second"},
+			"3": {ID: "3", Title: "Third"},
+		}}
+		api := api2go.NewAPI("v1")
+		api.AddResource(Post{}, source)
+		server = httptest.NewServer(api.Handler())
+
+		var err error
+		client, err = New(server.URL, "v1")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("gets one resource", func() {
+		var post Post
+		Expect(client.Get("1", &post)).To(Succeed())
+		Expect(post).To(Equal(Post{ID: "1", Title: "First", AuthorID: "1", CommentIDs: []string{"1"}}))
+	})
+
+	It("lists all resources", func() {
+		var posts []Post
+		Expect(client.List(&posts, nil)).To(Succeed())
+		Expect(posts).To(HaveLen(3))
+	})
+
+	It("follows the next links of paginated lists", func() {
+		var posts []Post
+		query := url.Values{"page[number]": {"1"}, "page[size]": {"2"}}
+		Expect(client.List(&posts, query)).To(Succeed())
+		Expect(posts).To(HaveLen(3))
+		Expect(posts[2].Title).To(Equal("Third"))
+	})
+
+	It("creates resources", func() {
+		post := Post{Title: "New"}
+		Expect(client.Create(&post)).To(Succeed())
+		Expect(post.ID).To(Equal("4"))
+		Expect(source.posts).To(HaveKey("4"))
+	})
+
+	It("takes the id from the location of resources created without content", func() {
+		post := Post{Title: "silent"}
+		Expect(client.Create(&post)).To(Succeed())
+		Expect(post.ID).To(Equal("4"))
+	})
+
+	It("updates resources", func() {
+		post := Post{ID: "2", Title: "Updated"}
+		Expect(client.Update(&post)).To(Succeed())
+		Expect(source.posts["2"].Title).To(Equal("Updated"))
+	})
+
+	It("deletes resources", func() {
+		Expect(client.Delete(Post{ID: "2"})).To(Succeed())
+		Expect(source.posts).ToNot(HaveKey("2"))
+	})
+
+	It("edits relationships", func() {
+		post := Post{ID: "1"}
+		Expect(client.ReplaceToOne(post, "author", "2")).To(Succeed())
+		Expect(source.posts["1"].AuthorID).To(Equal("2"))
+
+		Expect(client.AddToMany(post, "comments", []string{"2", "3"})).To(Succeed())
+		Expect(source.posts["1"].CommentIDs).To(Equal([]string{"1", "2", "3"}))
+
+		Expect(client.DeleteToMany(post, "comments", []string{"1"})).To(Succeed())
+		Expect(source.posts["1"].CommentIDs).To(Equal([]string{"2", "3"}))
+
+		Expect(client.ReplaceToMany(post, "comments", []string{})).To(Succeed())
+		Expect(source.posts["1"].CommentIDs).To(BeEmpty())
+
+		Expect(client.ReplaceToOne(post, "author", "")).To(Succeed())
+		Expect(source.posts["1"].AuthorID).To(BeEmpty())
+	})
+
+	It("rejects unknown relations", func() {
+		err := client.AddToMany(Post{ID: "1"}, "bananas", []string{"1"})
+		Expect(err).To(MatchError("client.Post has no relation bananas"))
+	})
+
+	It("decodes error responses into HTTPError", func() {
+		var post Post
+		err := client.Get("42", &post)
+		Expect(err).To(BeAssignableToTypeOf(api2go.HTTPError{}))
+		Expect(err.Error()).To(ContainSubstring("http error (404) post not found"))
+
+		err = client.Create(&Post{})
+		Expect(err).To(BeAssignableToTypeOf(api2go.HTTPError{}))
+		Expect(err.(api2go.HTTPError).Errors).To(Equal([]api2go.Error{{
+			Status: "422",
+			Title:  "title must not be empty",
+			Source: &api2go.ErrorSource{Pointer: "/data/attributes/title"},
+		}}))
+	})
+})
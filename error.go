@@ -1,11 +1,43 @@
 package api2go
 
 import (
+	"errors"
 	"fmt"
-	"log"
+	"net/http"
 	"strconv"
 )
 
+// Sentinel errors can be returned by resources, directly or wrapped like
+// fmt.Errorf("user %s: %w", id, api2go.ErrNotFound), to respond with their status code
+// instead of 500 Internal Server Error. An HTTPError with the same status code matches them
+// with errors.Is.
+var (
+	ErrBadRequest          = errors.New("bad request")
+	ErrUnauthorized        = errors.New("unauthorized")
+	ErrForbidden           = errors.New("forbidden")
+	ErrNotFound            = errors.New("not found")
+	ErrConflict            = errors.New("conflict")
+	ErrPreconditionFailed  = errors.New("precondition failed")
+	ErrUnprocessableEntity = errors.New("unprocessable entity")
+)
+
+// ErrDuplicateID can be returned by Create if a resource with the id generated by the client
+// already exists. It wraps ErrConflict and is answered with 409 Conflict pointing to /data/id.
+var ErrDuplicateID = fmt.Errorf("duplicate id: %w", ErrConflict)
+
+var sentinelErrors = []struct {
+	err    error
+	status int
+}{
+	{ErrBadRequest, http.StatusBadRequest},
+	{ErrUnauthorized, http.StatusUnauthorized},
+	{ErrForbidden, http.StatusForbidden},
+	{ErrNotFound, http.StatusNotFound},
+	{ErrConflict, http.StatusConflict},
+	{ErrPreconditionFailed, http.StatusPreconditionFailed},
+	{ErrUnprocessableEntity, http.StatusUnprocessableEntity},
+}
+
 //HTTPError is used for errors
 type HTTPError struct {
 	err    error
@@ -57,14 +89,29 @@ type ErrorSource struct {
 //MarshalError marshals errors recursively in json format.
 //it can make use of the jsonapi.HTTPError struct
 func (j JSONContentMarshaler) MarshalError(err error) string {
-	httpErr, ok := err.(HTTPError)
-	if ok {
-		return marshalHTTPError(httpErr, j)
+	return marshalHTTPError(AsHTTPError(err), j)
+}
+
+// AsHTTPError returns the HTTPError in the chain of err, or one with the status code of a
+// wrapped sentinel error. All other errors are 500 Internal Server Error. Errors of the
+// result contain at least one Error with the status code and message.
+func AsHTTPError(err error) HTTPError {
+	var httpErr HTTPError
+	if !errors.As(err, &httpErr) {
+		httpErr = NewHTTPError(err, err.Error(), http.StatusInternalServerError)
+		for _, sentinel := range sentinelErrors {
+			if errors.Is(err, sentinel.err) {
+				httpErr = NewHTTPError(err, http.StatusText(sentinel.status), sentinel.status)
+				break
+			}
+		}
 	}
 
-	httpErr = NewHTTPError(err, err.Error(), 500)
+	if len(httpErr.Errors) == 0 {
+		httpErr.Errors = []Error{{Title: httpErr.msg, Status: strconv.Itoa(httpErr.status)}}
+	}
 
-	return marshalHTTPError(httpErr, j)
+	return httpErr
 }
 
 //marshalHTTPError marshals an internal httpError
@@ -76,8 +123,11 @@ func marshalHTTPError(input HTTPError, marshaler ContentMarshaler) string {
 	data, err := marshaler.Marshal(input)
 
 	if err != nil {
-		log.Println(err)
-		return "{}"
+		// e.g. the meta of an error can not be marshalled, the client should at least get the status
+		data, err = marshaler.Marshal(HTTPError{Errors: []Error{{Title: input.msg, Status: strconv.Itoa(input.status)}}})
+		if err != nil {
+			return "{}"
+		}
 	}
 
 	return string(data)
@@ -99,3 +149,19 @@ func (e HTTPError) Error() string {
 
 	return msg
 }
+
+// Unwrap returns the error passed to NewHTTPError
+func (e HTTPError) Unwrap() error {
+	return e.err
+}
+
+// Is reports if target is the sentinel error of the status code of e, e.g. ErrNotFound for 404
+func (e HTTPError) Is(target error) bool {
+	for _, sentinel := range sentinelErrors {
+		if sentinel.err == target {
+			return sentinel.status == e.status
+		}
+	}
+
+	return false
+}
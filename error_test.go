@@ -2,6 +2,9 @@ package api2go
 
 import (
 	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -86,4 +89,109 @@ var _ = Describe("Errors test", func() {
 			Expect(result).To(Equal(expected))
 		})
 	})
+	Context("sentinel errors", func() {
+		It("uses the status code of wrapped sentinel errors", func() {
+			httpErr := AsHTTPError(fmt.Errorf("user 42: %w", ErrNotFound))
+			Expect(httpErr.status).To(Equal(404))
+			Expect(httpErr.msg).To(Equal("Not Found"))
+			Expect(errors.Is(httpErr, ErrNotFound)).To(BeTrue())
+		})
+
+		It("finds wrapped http errors", func() {
+			httpErr := AsHTTPError(fmt.Errorf("saving: %w", NewHTTPError(nil, "Taken", 409)))
+			Expect(httpErr.status).To(Equal(409))
+			Expect(httpErr.msg).To(Equal("Taken"))
+		})
+
+		It("uses 500 for other errors", func() {
+			httpErr := AsHTTPError(errors.New("connection refused"))
+			Expect(httpErr.status).To(Equal(500))
+			Expect(httpErr.msg).To(Equal("connection refused"))
+		})
+
+		It("matches http errors with sentinel errors of their status code", func() {
+			httpErr := NewHTTPError(nil, "Post is locked", 403)
+			Expect(errors.Is(httpErr, ErrForbidden)).To(BeTrue())
+			Expect(errors.Is(httpErr, ErrNotFound)).To(BeFalse())
+		})
+
+		It("unwraps the logged error", func() {
+			cause := errors.New("disk full")
+			Expect(errors.Unwrap(NewHTTPError(cause, "Failed", 500))).To(Equal(cause))
+			Expect(errors.Is(NewHTTPError(cause, "Failed", 500), cause)).To(BeTrue())
+		})
+	})
+	Context("error handler and logger", func() {
+		var (
+			api    *API
+			rec    *httptest.ResponseRecorder
+			logger *recordingLogger
+		)
+
+		BeforeEach(func() {
+			api = NewAPI("v1")
+			api.AddResource(Post{}, &fixtureSource{posts: map[string]*Post{}})
+			logger = &recordingLogger{}
+			api.SetLogger(logger)
+			rec = httptest.NewRecorder()
+		})
+
+		doRequest := func() {
+			req, err := http.NewRequest("GET", "/v1/posts/42", nil)
+			Expect(err).ToNot(HaveOccurred())
+			req.Header.Set("X-Request-Id", "abc")
+			api.Handler().ServeHTTP(rec, req)
+		}
+
+		It("logs errors with the logger", func() {
+			doRequest()
+			Expect(rec.Code).To(Equal(http.StatusNotFound))
+			Expect(logger.messages).To(Equal([]string{"request failed"}))
+			Expect(logger.args[0]).To(ContainElement("/v1/posts/42"))
+			Expect(logger.args[0]).To(ContainElement(http.StatusNotFound))
+			Expect(logger.args[0]).To(ContainElement("read"))
+		})
+
+		It("sends the result of the error handler", func() {
+			var (
+				original error
+				routes   []Route
+			)
+			api.SetErrorHandler(func(err error, r *http.Request, route Route) HTTPError {
+				original = err
+				routes = append(routes, route)
+
+				httpErr := AsHTTPError(err)
+				for i := range httpErr.Errors {
+					httpErr.Errors[i].ID = r.Header.Get("X-Request-Id")
+				}
+				return httpErr
+			})
+			doRequest()
+			Expect(rec.Code).To(Equal(http.StatusNotFound))
+			Expect(original.Error()).To(ContainSubstring("post not found"))
+			Expect(routes).To(Equal([]Route{{Resource: "posts", Operation: OperationRead}}))
+			Expect(rec.Body.Bytes()).To(MatchJSON(`{"errors": [{"id": "abc", "status": "404", "title": "post not found"}]}`))
+			Expect(logger.args[0]).To(ContainElement(http.StatusNotFound))
+		})
+
+		It("sends the status of the original error if the error handler sets none", func() {
+			api.SetErrorHandler(func(err error, r *http.Request, route Route) HTTPError {
+				return HTTPError{Errors: []Error{{Title: "Something went wrong"}}}
+			})
+			doRequest()
+			Expect(rec.Code).To(Equal(http.StatusNotFound))
+			Expect(rec.Body.Bytes()).To(MatchJSON(`{"errors": [{"title": "Something went wrong"}]}`))
+		})
+	})
 })
+
+type recordingLogger struct {
+	messages []string
+	args     [][]interface{}
+}
+
+func (l *recordingLogger) Error(msg string, args ...interface{}) {
+	l.messages = append(l.messages, msg)
+	l.args = append(l.args, args)
+}
@@ -74,6 +74,14 @@ var _ = Describe("CrudExample", func() {
 		createUser()
 	})
 
+	It("Answers 404 for unknown users", func() {
+		req, err := http.NewRequest("GET", "/v0/users/42", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusNotFound))
+		Expect(rec.Body.String()).To(MatchJSON(`{"errors": [{"status": "404", "title": "Not Found"}]}`))
+	})
+
 	var createChocolate = func() {
 		rec = httptest.NewRecorder()
 		req, err := http.NewRequest("POST", "/v0/chocolates", strings.NewReader(`
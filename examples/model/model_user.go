@@ -10,7 +10,7 @@ import (
 type User struct {
 	ID string
 	//rename the username field to user-name.
-	Username      string       `jsonapi:"name=user-name"`
+	Username      string       `jsonapi:"name=user-name;required;maxlen=40"`
 	PasswordHash  string       `json:"-"`
 	Chocolates    []*Chocolate `json:"-"`
 	ChocolatesIDs []string     `json:"-"`
@@ -41,9 +41,8 @@ func (s UserResource) FindAll(r api2go.Request) (api2go.Responder, error) {
 // PaginatedFindAll can be used to load users in chunks
 func (s UserResource) PaginatedFindAll(r api2go.Request) (uint, api2go.Responder, error) {
 	var (
-		result                      []model.User
-		number, size, offset, limit string
-		keys                        []int
+		result []model.User
+		keys   []int
 	)
 	users := s.UserStorage.GetAll()
 
@@ -57,58 +56,15 @@ func (s UserResource) PaginatedFindAll(r api2go.Request) (uint, api2go.Responder
 	}
 	sort.Ints(keys)
 
-	numberQuery, ok := r.QueryParams["page[number]"]
-	if ok {
-		number = numberQuery[0]
+	// api2go already validated the page parameters, either number and size or offset and limit are set
+	start, end := r.Pagination.Offset, r.Pagination.Offset+r.Pagination.Limit
+	if r.Pagination.Size > 0 {
+		start = r.Pagination.Size * (r.Pagination.Number - 1)
+		end = start + r.Pagination.Size
 	}
-	sizeQuery, ok := r.QueryParams["page[size]"]
-	if ok {
-		size = sizeQuery[0]
-	}
-	offsetQuery, ok := r.QueryParams["page[offset]"]
-	if ok {
-		offset = offsetQuery[0]
-	}
-	limitQuery, ok := r.QueryParams["page[limit]"]
-	if ok {
-		limit = limitQuery[0]
-	}
-
-	if size != "" {
-		sizeI, err := strconv.ParseUint(size, 10, 64)
-		if err != nil {
-			return 0, &Response{}, err
-		}
-
-		numberI, err := strconv.ParseUint(number, 10, 64)
-		if err != nil {
-			return 0, &Response{}, err
-		}
 
-		start := sizeI * (numberI - 1)
-		for i := start; i < start+sizeI; i++ {
-			if i >= uint64(len(users)) {
-				break
-			}
-			result = append(result, *users[strconv.FormatInt(int64(keys[i]), 10)])
-		}
-	} else {
-		limitI, err := strconv.ParseUint(limit, 10, 64)
-		if err != nil {
-			return 0, &Response{}, err
-		}
-
-		offsetI, err := strconv.ParseUint(offset, 10, 64)
-		if err != nil {
-			return 0, &Response{}, err
-		}
-
-		for i := offsetI; i < offsetI+limitI; i++ {
-			if i >= uint64(len(users)) {
-				break
-			}
-			result = append(result, *users[strconv.FormatInt(int64(keys[i]), 10)])
-		}
+	for i := start; i < end && i < uint(len(keys)); i++ {
+		result = append(result, *users[strconv.FormatInt(int64(keys[i]), 10)])
 	}
 
 	return uint(len(users)), &Response{Res: result}, nil
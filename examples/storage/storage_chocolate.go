@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"sort"
 
+	"github.com/manyminds/api2go"
 	"github.com/manyminds/api2go/examples/model"
 )
 
@@ -52,7 +53,7 @@ func (s ChocolateStorage) GetOne(id string) (model.Chocolate, error) {
 		return *choc, nil
 	}
 
-	return model.Chocolate{}, fmt.Errorf("Chocolate for id %s not found", id)
+	return model.Chocolate{}, fmt.Errorf("Chocolate for id %s: %w", id, api2go.ErrNotFound)
 }
 
 // Insert a fresh one
@@ -68,7 +69,7 @@ func (s *ChocolateStorage) Insert(c model.Chocolate) string {
 func (s *ChocolateStorage) Delete(id string) error {
 	_, exists := s.chocolates[id]
 	if !exists {
-		return fmt.Errorf("Chocolate with id %s does not exist", id)
+		return fmt.Errorf("Chocolate with id %s: %w", id, api2go.ErrNotFound)
 	}
 	delete(s.chocolates, id)
 
@@ -79,7 +80,7 @@ func (s *ChocolateStorage) Delete(id string) error {
 func (s *ChocolateStorage) Update(c model.Chocolate) error {
 	_, exists := s.chocolates[c.ID]
 	if !exists {
-		return fmt.Errorf("Chocolate with id %s does not exist", c.ID)
+		return fmt.Errorf("Chocolate with id %s: %w", c.ID, api2go.ErrNotFound)
 	}
 	s.chocolates[c.ID] = &c
 
@@ -3,6 +3,7 @@ package storage
 import (
 	"fmt"
 
+	"github.com/manyminds/api2go"
 	"github.com/manyminds/api2go/examples/model"
 )
 
@@ -29,7 +30,7 @@ func (s UserStorage) GetOne(id string) (model.User, error) {
 		return *user, nil
 	}
 
-	return model.User{}, fmt.Errorf("User for id %s not found", id)
+	return model.User{}, fmt.Errorf("User for id %s: %w", id, api2go.ErrNotFound)
 }
 
 // Insert a user
@@ -45,7 +46,7 @@ func (s *UserStorage) Insert(c model.User) string {
 func (s *UserStorage) Delete(id string) error {
 	_, exists := s.users[id]
 	if !exists {
-		return fmt.Errorf("User with id %s does not exist", id)
+		return fmt.Errorf("User with id %s: %w", id, api2go.ErrNotFound)
 	}
 	delete(s.users, id)
 
@@ -56,7 +57,7 @@ func (s *UserStorage) Delete(id string) error {
 func (s *UserStorage) Update(c model.User) error {
 	_, exists := s.users[c.ID]
 	if !exists {
-		return fmt.Errorf("User with id %s does not exist", c.ID)
+		return fmt.Errorf("User with id %s: %w", c.ID, api2go.ErrNotFound)
 	}
 	s.users[c.ID] = &c
 
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 )
 
 // MarshalIdentifier interface is necessary to give an element
@@ -55,6 +56,25 @@ type MarshalIncludedRelations interface {
 	GetReferencedStructs() []MarshalIdentifier
 }
 
+// MarshalOptions can be passed to MarshalWithOptions to control which parts of a document are generated
+type MarshalOptions struct {
+	// Include contains relationship paths like `comments` or `author.profile`. Only the referenced structs
+	// on these paths will be added to `included`, nested paths are resolved with the referenced structs of
+	// the included structs. If Include is nil, all structs returned by GetReferencedStructs are included.
+	Include []string
+	// Fields maps a type to the names of the attributes and relationships that should be generated for
+	// every element of that type, in `data` as well as in `included`. Types without an entry are not
+	// restricted.
+	Fields map[string][]string
+}
+
+// marshalFunc returns a marshal function for reduceDuplicates that applies the options
+func (o MarshalOptions) marshalFunc() func(MarshalIdentifier, ServerInformation) (map[string]interface{}, error) {
+	return func(element MarshalIdentifier, information ServerInformation) (map[string]interface{}, error) {
+		return marshalData(element, information, o)
+	}
+}
+
 // ServerInformation can be passed to MarshalWithURLs to generate the `self` and `related` urls inside `links`
 type ServerInformation interface {
 	GetBaseURL() string
@@ -86,32 +106,38 @@ func MarshalToJSONWithURLs(val interface{}, information ServerInformation) ([]by
 
 // MarshalWithURLs can be used to include the generation of `related` and `self` links
 func MarshalWithURLs(data interface{}, information ServerInformation) (map[string]interface{}, error) {
-	return marshal(data, information)
+	return marshal(data, information, MarshalOptions{})
+}
+
+// MarshalWithOptions works like `MarshalWithURLs` but additionally applies the given options, `information`
+// can be nil if no links should be generated.
+func MarshalWithOptions(data interface{}, information ServerInformation, options MarshalOptions) (map[string]interface{}, error) {
+	return marshal(data, information, options)
 }
 
 // Marshal thats the input from `data` which can be a struct, a slice, or a pointer of it.
 // Any struct in `data`or data itself, must at least implement the `MarshalIdentifier` interface.
 // If so, it will generate a map[string]interface{} matching the jsonapi specification.
 func Marshal(data interface{}) (map[string]interface{}, error) {
-	return marshal(data, serverInformationNil)
+	return marshal(data, serverInformationNil, MarshalOptions{})
 }
 
-func marshal(data interface{}, information ServerInformation) (map[string]interface{}, error) {
+func marshal(data interface{}, information ServerInformation, options MarshalOptions) (map[string]interface{}, error) {
 	if data == nil {
 		return map[string]interface{}{}, errors.New("nil cannot be marshalled")
 	}
 
 	switch reflect.TypeOf(data).Kind() {
 	case reflect.Slice:
-		return marshalSlice(data, information)
+		return marshalSlice(data, information, options)
 	case reflect.Struct, reflect.Ptr:
-		return marshalStruct(data.(MarshalIdentifier), information)
+		return marshalStruct(data.(MarshalIdentifier), information, options)
 	default:
 		return map[string]interface{}{}, errors.New("Marshal only accepts slice, struct or ptr types")
 	}
 }
 
-func marshalSlice(data interface{}, information ServerInformation) (map[string]interface{}, error) {
+func marshalSlice(data interface{}, information ServerInformation, options MarshalOptions) (map[string]interface{}, error) {
 	result := make(map[string]interface{})
 
 	val := reflect.ValueOf(data)
@@ -121,6 +147,7 @@ func marshalSlice(data interface{}, information ServerInformation) (map[string]i
 
 	dataElements := []map[string]interface{}{}
 	var referencedStructs []MarshalIdentifier
+	paths := newIncludePaths(options.Include)
 
 	for i := 0; i < val.Len(); i++ {
 		k := val.Index(i).Interface()
@@ -129,20 +156,25 @@ func marshalSlice(data interface{}, information ServerInformation) (map[string]i
 			return result, errors.New("all elements within the slice must implement api2go.MarshalIdentifier")
 		}
 
-		content, err := marshalData(element, information)
+		content, err := marshalData(element, information, options)
 		if err != nil {
 			return result, err
 		}
 
 		dataElements = append(dataElements, content)
 
+		if options.Include != nil {
+			referencedStructs = append(referencedStructs, getIncludedStructsForPaths(element, paths)...)
+			continue
+		}
+
 		included, ok := k.(MarshalIncludedRelations)
 		if ok {
 			referencedStructs = append(referencedStructs, included.GetReferencedStructs()...)
 		}
 	}
 
-	includedElements, err := reduceDuplicates(referencedStructs, information, marshalData)
+	includedElements, err := reduceDuplicates(referencedStructs, information, options.marshalFunc())
 	if err != nil {
 		return result, err
 	}
@@ -194,7 +226,7 @@ func reduceDuplicates(
 	return includedElements, nil
 }
 
-func marshalData(element MarshalIdentifier, information ServerInformation) (map[string]interface{}, error) {
+func marshalData(element MarshalIdentifier, information ServerInformation, options MarshalOptions) (map[string]interface{}, error) {
 	result := make(map[string]interface{})
 
 	refValue := reflect.ValueOf(element)
@@ -203,27 +235,53 @@ func marshalData(element MarshalIdentifier, information ServerInformation) (map[
 	}
 
 	id := element.GetID()
+	structType := getStructType(element)
+	fields, isSparse := options.Fields[structType]
 	content := getStructFields(element)
 	result["attributes"] = make(map[string]interface{})
 	attributes := result["attributes"].(map[string]interface{})
 	// if there is a field name `id` that is not ignored by the json ignore flag, it gets into the
 	// attributes as well, this is a intended behavior.
 	for k, v := range content {
+		if isSparse && !containsField(fields, k) {
+			continue
+		}
 		attributes[k] = v
 	}
 
 	result["id"] = id
-	result["type"] = getStructType(element)
+	result["type"] = structType
 
 	// optional relationship interface for struct
 	references, ok := element.(MarshalLinkedRelations)
 	if ok {
-		result["relationships"] = getStructRelationships(references, information)
+		relationships := getStructRelationships(references, information)
+		if isSparse {
+			for name := range relationships {
+				if !containsField(fields, name) {
+					delete(relationships, name)
+				}
+			}
+		}
+
+		if !isSparse || len(relationships) > 0 {
+			result["relationships"] = relationships
+		}
 	}
 
 	return result, nil
 }
 
+func containsField(fields []string, name string) bool {
+	for _, field := range fields {
+		if field == name {
+			return true
+		}
+	}
+
+	return false
+}
+
 // getStructRelationships returns the relationships struct with ids
 func getStructRelationships(relationer MarshalLinkedRelations, information ServerInformation) map[string]map[string]interface{} {
 	referencedIDs := relationer.GetReferencedIDs()
@@ -325,12 +383,12 @@ func getLinksForServerInformation(relationer MarshalLinkedRelations, name string
 	return links
 }
 
-func getIncludedStructs(included MarshalIncludedRelations, information ServerInformation) ([]map[string]interface{}, error) {
+func getIncludedStructs(included MarshalIncludedRelations, information ServerInformation, options MarshalOptions) ([]map[string]interface{}, error) {
 	var result = make([]map[string]interface{}, 0)
 	includedStructs := included.GetReferencedStructs()
 
 	for key := range includedStructs {
-		marshalled, err := marshalData(includedStructs[key], information)
+		marshalled, err := marshalData(includedStructs[key], information, options)
 		if err != nil {
 			return result, err
 		}
@@ -341,18 +399,106 @@ func getIncludedStructs(included MarshalIncludedRelations, information ServerInf
 	return result, nil
 }
 
-func marshalStruct(data MarshalIdentifier, information ServerInformation) (map[string]interface{}, error) {
+// includePaths is a tree of relationship names built from include paths like `author.profile`
+type includePaths map[string]includePaths
+
+func newIncludePaths(include []string) includePaths {
+	result := includePaths{}
+	for _, path := range include {
+		current := result
+		for _, name := range strings.Split(path, ".") {
+			if current[name] == nil {
+				current[name] = includePaths{}
+			}
+			current = current[name]
+		}
+	}
+
+	return result
+}
+
+// getIncludedStructsForPaths returns all referenced structs of element which can be reached by following the
+// relationships in paths. The relationship of a referenced struct is looked up by its type and id in
+// GetReferencedIDs, or by its type in GetReferences if GetReferencedIDs is not available.
+func getIncludedStructsForPaths(element MarshalIdentifier, paths includePaths) []MarshalIdentifier {
+	var result []MarshalIdentifier
+
+	if len(paths) == 0 {
+		return result
+	}
+
+	included, ok := element.(MarshalIncludedRelations)
+	if !ok {
+		return result
+	}
+
+	names := map[string][]string{}
+	if linked, ok := element.(MarshalLinkedRelations); ok {
+		for _, referenceID := range linked.GetReferencedIDs() {
+			key := referenceID.Type + "/" + referenceID.ID
+			names[key] = append(names[key], referenceID.Name)
+		}
+	} else {
+		for _, reference := range included.GetReferences() {
+			names[reference.Type] = append(names[reference.Type], reference.Name)
+		}
+	}
+
+	for _, referencedStruct := range included.GetReferencedStructs() {
+		if referencedStruct == nil {
+			continue
+		}
+
+		structType := getStructType(referencedStruct)
+		relationNames, ok := names[structType+"/"+referencedStruct.GetID()]
+		if !ok {
+			relationNames = names[structType]
+		}
+
+		isIncluded := false
+		for _, name := range relationNames {
+			subPaths, ok := paths[name]
+			if !ok {
+				continue
+			}
+
+			if !isIncluded {
+				result = append(result, referencedStruct)
+				isIncluded = true
+			}
+			result = append(result, getIncludedStructsForPaths(referencedStruct, subPaths)...)
+		}
+	}
+
+	return result
+}
+
+func marshalStruct(data MarshalIdentifier, information ServerInformation, options MarshalOptions) (map[string]interface{}, error) {
 	result := make(map[string]interface{})
-	contentData, err := marshalData(data, information)
+	contentData, err := marshalData(data, information, options)
 	if err != nil {
 		return result, err
 	}
 
 	result["data"] = contentData
 
+	if options.Include != nil {
+		referencedStructs := getIncludedStructsForPaths(data, newIncludePaths(options.Include))
+		included, err := reduceDuplicates(referencedStructs, information, options.marshalFunc())
+		if err != nil {
+			return result, err
+		}
+
+		if len(included) > 0 {
+			result["included"] = included
+		}
+
+		return result, nil
+	}
+
 	included, ok := data.(MarshalIncludedRelations)
 	if ok {
-		included, err := getIncludedStructs(included, information)
+		included, err := getIncludedStructs(included, information, options)
 		if err != nil {
 			return result, err
 		}
@@ -385,27 +531,42 @@ func getStructFields(data MarshalIdentifier) map[string]interface{} {
 	if val.Kind() == reflect.Ptr {
 		val = val.Elem()
 	}
-	valType := val.Type()
-	for i := 0; i < val.NumField(); i++ {
-		tag := valType.Field(i).Tag.Get("json")
+
+	for keyName, field := range AttributeFields(data) {
+		result[keyName] = val.FieldByIndex(field.Index).Interface()
+	}
+
+	return result
+}
+
+// AttributeFields returns the struct fields of data that are marshalled as attributes,
+// mapped by their attribute names
+func AttributeFields(data MarshalIdentifier) map[string]reflect.StructField {
+	result := make(map[string]reflect.StructField)
+	valType := reflect.TypeOf(data)
+	if valType.Kind() == reflect.Ptr {
+		valType = valType.Elem()
+	}
+
+	for i := 0; i < valType.NumField(); i++ {
+		field := valType.Field(i)
+		tag := field.Tag.Get("json")
 		if tag == "-" {
 			continue
 		}
 
-		field := val.Field(i)
-		keyName := Jsonify(valType.Field(i).Name)
-
 		//skip private fields
-		if !field.CanInterface() {
+		if field.PkgPath != "" {
 			continue
 		}
 
-		name := GetTagValueByName(valType.Field(i), "name")
+		keyName := Jsonify(field.Name)
+		name := GetTagValueByName(field, "name")
 		if name != "" {
 			keyName = name
 		}
 
-		result[keyName] = field.Interface()
+		result[keyName] = field
 	}
 
 	return result
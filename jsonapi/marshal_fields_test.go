@@ -0,0 +1,67 @@
+package jsonapi
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Marshalling with sparse fieldsets", func() {
+	var post Post
+
+	BeforeEach(func() {
+		post = Post{
+			ID:       1,
+			Title:    "Foobar",
+			Author:   &User{ID: 1, Name: "Test Author"},
+			Comments: []Comment{{ID: 1, Text: "First!"}},
+		}
+	})
+
+	It("marshals only the requested attributes and relationships", func() {
+		i, err := MarshalWithOptions(post, nil, MarshalOptions{Fields: map[string][]string{"posts": {"comments"}}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(i["data"]).To(Equal(map[string]interface{}{
+			"id":         "1",
+			"type":       "posts",
+			"attributes": map[string]interface{}{},
+			"relationships": map[string]map[string]interface{}{
+				"comments": {
+					"data": []map[string]interface{}{
+						{"id": "1", "type": "comments"},
+					},
+				},
+			},
+		}))
+	})
+
+	It("omits relationships if none of them were requested", func() {
+		i, err := MarshalWithOptions(post, nil, MarshalOptions{Fields: map[string][]string{"posts": {"title"}}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(i["data"]).To(Equal(map[string]interface{}{
+			"id":   "1",
+			"type": "posts",
+			"attributes": map[string]interface{}{
+				"title": "Foobar",
+			},
+		}))
+	})
+
+	It("applies the fieldsets to included structs", func() {
+		i, err := MarshalWithOptions([]Post{post}, nil, MarshalOptions{Fields: map[string][]string{"users": {}}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(i["included"]).To(Equal([]map[string]interface{}{
+			{
+				"id":         "1",
+				"type":       "users",
+				"attributes": map[string]interface{}{},
+			},
+			{
+				"id":   "1",
+				"type": "comments",
+				"attributes": map[string]interface{}{
+					"text": "First!",
+				},
+			},
+		}))
+	})
+})
@@ -0,0 +1,94 @@
+package jsonapi
+
+import (
+	"database/sql"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Marshalling with include paths", func() {
+	var (
+		post          Post
+		authorMap     map[string]interface{}
+		firstComment  map[string]interface{}
+		secondComment map[string]interface{}
+	)
+
+	BeforeEach(func() {
+		post = Post{
+			ID:       1,
+			Title:    "Foobar",
+			Author:   &User{ID: 1, Name: "Test Author"},
+			Comments: []Comment{{ID: 1, Text: "First!"}, {ID: 2, Text: "Second!"}},
+		}
+
+		authorMap = map[string]interface{}{
+			"id":   "1",
+			"type": "users",
+			"attributes": map[string]interface{}{
+				"name": "Test Author",
+			},
+		}
+
+		firstComment = map[string]interface{}{
+			"id":   "1",
+			"type": "comments",
+			"attributes": map[string]interface{}{
+				"text": "First!",
+			},
+		}
+
+		secondComment = map[string]interface{}{
+			"id":   "2",
+			"type": "comments",
+			"attributes": map[string]interface{}{
+				"text": "Second!",
+			},
+		}
+	})
+
+	It("includes everything without include paths", func() {
+		i, err := MarshalWithOptions(post, nil, MarshalOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(i["included"]).To(Equal([]map[string]interface{}{authorMap, firstComment, secondComment}))
+	})
+
+	It("includes nothing with empty include paths", func() {
+		i, err := MarshalWithOptions(post, nil, MarshalOptions{Include: []string{}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(i).ToNot(HaveKey("included"))
+	})
+
+	It("includes only the requested relation of a struct", func() {
+		i, err := MarshalWithOptions(post, nil, MarshalOptions{Include: []string{"comments"}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(i["included"]).To(Equal([]map[string]interface{}{firstComment, secondComment}))
+	})
+
+	It("includes only the requested relation of a slice without duplicates", func() {
+		secondPost := post
+		secondPost.ID = 2
+		i, err := MarshalWithOptions([]Post{post, secondPost}, nil, MarshalOptions{Include: []string{"author"}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(i["included"]).To(Equal([]map[string]interface{}{authorMap}))
+	})
+
+	It("includes nested relations", func() {
+		first := Question{ID: "1", Text: "Does it work?"}
+		second := Question{ID: "2", Text: "Will it work?", InspiringQuestionID: sql.NullString{String: "1", Valid: true}, InspiringQuestion: &first}
+		third := Question{ID: "3", Text: "It works?", InspiringQuestionID: sql.NullString{String: "2", Valid: true}, InspiringQuestion: &second}
+
+		i, err := MarshalWithOptions(third, nil, MarshalOptions{Include: []string{"inspiringQuestion"}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(i["included"]).To(HaveLen(1))
+
+		i, err = MarshalWithOptions(third, nil, MarshalOptions{Include: []string{"inspiringQuestion.inspiringQuestion"}})
+		Expect(err).ToNot(HaveOccurred())
+		included, ok := i["included"].([]map[string]interface{})
+		Expect(ok).To(BeTrue())
+		Expect(included).To(HaveLen(2))
+		Expect(included[0]["id"]).To(Equal("2"))
+		Expect(included[1]["id"]).To(Equal("1"))
+	})
+})
@@ -721,6 +721,16 @@ var _ = Describe("Marshalling", func() {
 		})
 	})
 
+	Context("test AttributeFields method", func() {
+		It("returns the fields by attribute name", func() {
+			result := AttributeFields(&SimplePost{})
+			Expect(result).To(HaveLen(4))
+			Expect(result["title"].Name).To(Equal("Title"))
+			Expect(result["create-date"].Name).To(Equal("Created"))
+			Expect(result).ToNot(HaveKey("id"))
+		})
+	})
+
 	Context("test getStructLinks", func() {
 		var (
 			post    Post
@@ -824,7 +834,7 @@ var _ = Describe("Marshalling", func() {
 		}
 
 		It("should work with default marshalData", func() {
-			actual, err := reduceDuplicates(input, serverInformationNil, marshalData)
+			actual, err := reduceDuplicates(input, serverInformationNil, MarshalOptions{}.marshalFunc())
 			Expect(err).ToNot(HaveOccurred())
 			Expect(len(actual)).To(Equal(len(expected)))
 		})
@@ -0,0 +1,260 @@
+package jsonapi
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DocumentError is a violation of the structure rules of JSON:API, Pointer is a JSON Pointer
+// to the offending member of the document
+type DocumentError struct {
+	Pointer string
+	Detail  string
+}
+
+// Error returns the pointer and the detail of the violation
+func (e DocumentError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pointer, e.Detail)
+}
+
+var (
+	topLevelMembers       = []string{"data", "errors", "meta", "jsonapi", "links", "included"}
+	resourceObjectMembers = []string{"type", "id", "lid", "attributes", "relationships", "links", "meta"}
+	identifierMembers     = []string{"type", "id", "lid", "meta"}
+	relationshipMembers   = []string{"data", "links", "meta"}
+)
+
+// ValidateRequest checks a request document that creates or updates resources against the structure
+// rules of JSON:API 1.1 and returns all violations. The primary data must be a resource object or an
+// array of them, the relationships of resource objects must contain resource linkage in data.
+//
+// Members whose name contains a colon belong to an extension and are not checked.
+func ValidateRequest(doc map[string]interface{}) []DocumentError {
+	v := &validator{}
+	if v.topLevel(doc) {
+		switch data := doc["data"].(type) {
+		case []interface{}:
+			for i, element := range data {
+				v.resourceObject(element, fmt.Sprintf("/data/%d", i))
+			}
+		default:
+			v.resourceObject(data, "/data")
+		}
+	}
+
+	return v.errors
+}
+
+// ValidateUpdateRequest checks a request document that updates a resource like ValidateRequest, but
+// the primary data must be a single resource object with an id.
+func ValidateUpdateRequest(doc map[string]interface{}) []DocumentError {
+	v := &validator{update: true}
+	if v.topLevel(doc) {
+		if _, ok := doc["data"].([]interface{}); ok {
+			v.fail("/data", "data of update requests must be a resource object")
+		} else {
+			v.resourceObject(doc["data"], "/data")
+		}
+	}
+
+	return v.errors
+}
+
+// ValidateRelationshipRequest checks a request document that updates a relationship against the
+// structure rules of JSON:API 1.1 and returns all violations. The primary data must be resource
+// linkage, which is null, a resource identifier object or an array of them.
+func ValidateRelationshipRequest(doc map[string]interface{}) []DocumentError {
+	v := &validator{}
+	if v.topLevel(doc) {
+		v.linkage(doc["data"], "/data")
+	}
+
+	return v.errors
+}
+
+type validator struct {
+	// update requires an id for the resource object of the primary data
+	update bool
+	errors []DocumentError
+}
+
+func (v *validator) fail(pointer, format string, args ...interface{}) {
+	v.errors = append(v.errors, DocumentError{Pointer: pointer, Detail: fmt.Sprintf(format, args...)})
+}
+
+// topLevel checks the top-level members of a request document and returns if it has data
+func (v *validator) topLevel(doc map[string]interface{}) bool {
+	v.members(doc, "", topLevelMembers, "top-level member")
+	v.object(doc, "meta", "")
+	v.object(doc, "jsonapi", "")
+	v.object(doc, "links", "")
+
+	if _, ok := doc["errors"]; ok {
+		v.fail("/errors", "errors are not allowed in request documents")
+	}
+
+	if _, ok := doc["data"]; !ok {
+		v.fail("/data", "request documents must contain data")
+		return false
+	}
+
+	return true
+}
+
+// resourceObject checks a resource object of the primary data
+func (v *validator) resourceObject(value interface{}, pointer string) {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		v.fail(pointer, "resource object must be an object")
+		return
+	}
+
+	v.members(object, pointer, resourceObjectMembers, "member of a resource object")
+	v.typeAndID(object, pointer, false)
+	v.object(object, "meta", pointer)
+	v.object(object, "links", pointer)
+
+	attributes, hasAttributes := object["attributes"].(map[string]interface{})
+	if _, ok := object["attributes"]; ok && !hasAttributes {
+		v.fail(pointer+"/attributes", "attributes must be an object")
+	}
+
+	relationships, hasRelationships := object["relationships"].(map[string]interface{})
+	if _, ok := object["relationships"]; ok && !hasRelationships {
+		v.fail(pointer+"/relationships", "relationships must be an object")
+	}
+
+	for _, name := range sortedKeys(attributes) {
+		switch {
+		case name == "id" || name == "type":
+			v.fail(pointer+"/attributes/"+escapePointer(name), "attribute must not be named %s", name)
+		case relationships[name] != nil:
+			v.fail(pointer+"/attributes/"+escapePointer(name), "%s must not be an attribute and a relationship", name)
+		}
+	}
+
+	for _, name := range sortedKeys(relationships) {
+		relationshipPointer := pointer + "/relationships/" + escapePointer(name)
+		if name == "id" || name == "type" {
+			v.fail(relationshipPointer, "relationship must not be named %s", name)
+			continue
+		}
+
+		relationship, ok := relationships[name].(map[string]interface{})
+		if !ok {
+			v.fail(relationshipPointer, "relationship must be an object")
+			continue
+		}
+
+		v.members(relationship, relationshipPointer, relationshipMembers, "member of a relationship")
+		v.object(relationship, "meta", relationshipPointer)
+		v.object(relationship, "links", relationshipPointer)
+		if _, ok := relationship["data"]; !ok {
+			v.fail(relationshipPointer+"/data", "relationships of request documents must contain data")
+			continue
+		}
+
+		v.linkage(relationship["data"], relationshipPointer+"/data")
+	}
+}
+
+// linkage checks resource linkage, which is null, a resource identifier object or an array of them
+func (v *validator) linkage(value interface{}, pointer string) {
+	switch value := value.(type) {
+	case nil:
+	case map[string]interface{}:
+		v.identifier(value, pointer)
+	case []interface{}:
+		for i, element := range value {
+			elementPointer := fmt.Sprintf("%s/%d", pointer, i)
+			identifier, ok := element.(map[string]interface{})
+			if !ok {
+				v.fail(elementPointer, "resource identifier must be an object")
+				continue
+			}
+
+			v.identifier(identifier, elementPointer)
+		}
+	default:
+		v.fail(pointer, "resource linkage must be null, an object or an array")
+	}
+}
+
+func (v *validator) identifier(object map[string]interface{}, pointer string) {
+	v.members(object, pointer, identifierMembers, "member of a resource identifier")
+	v.typeAndID(object, pointer, true)
+	v.object(object, "meta", pointer)
+}
+
+// typeAndID checks that type is a string, and id or lid if they are present. Resource identifiers
+// need one of id or lid, resource objects need an id in updates and may have neither otherwise.
+func (v *validator) typeAndID(object map[string]interface{}, pointer string, identifier bool) {
+	if objectType, ok := object["type"]; !ok {
+		v.fail(pointer+"/type", "type is missing")
+	} else if objectType, ok := objectType.(string); !ok || objectType == "" {
+		v.fail(pointer+"/type", "type must be a non-empty string")
+	}
+
+	id, hasID := object["id"]
+	if _, ok := id.(string); hasID && !ok {
+		v.fail(pointer+"/id", "id must be a string")
+	}
+
+	lid, hasLID := object["lid"]
+	if _, ok := lid.(string); hasLID && !ok {
+		v.fail(pointer+"/lid", "lid must be a string")
+	}
+
+	if !hasID && (identifier && !hasLID || !identifier && v.update) {
+		v.fail(pointer+"/id", "id is missing")
+	}
+}
+
+// object checks that the optional member name of parent is an object
+func (v *validator) object(parent map[string]interface{}, name, pointer string) {
+	value, ok := parent[name]
+	if !ok {
+		return
+	}
+
+	if _, ok := value.(map[string]interface{}); !ok {
+		v.fail(pointer+"/"+name, "%s must be an object", name)
+	}
+}
+
+// members reports all members of object that are neither allowed nor belong to an extension
+func (v *validator) members(object map[string]interface{}, pointer string, allowed []string, kind string) {
+	for _, name := range sortedKeys(object) {
+		if strings.Contains(name, ":") || contains(allowed, name) {
+			continue
+		}
+
+		v.fail(pointer+"/"+escapePointer(name), "%s is not a valid %s", name, kind)
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// escapePointer escapes a member name for a JSON Pointer
+func escapePointer(name string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(name)
+}
@@ -0,0 +1,127 @@
+package jsonapi
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Validating request documents", func() {
+	parse := func(content string) map[string]interface{} {
+		var doc map[string]interface{}
+		Expect(json.Unmarshal([]byte(content), &doc)).To(Succeed())
+		return doc
+	}
+
+	Context("resource documents", func() {
+		It("accepts valid documents", func() {
+			Expect(ValidateRequest(parse(`{
+				"data": {
+					"type": "posts",
+					"id": "1",
+					"attributes": {"title": "Hello"},
+					"relationships": {
+						"author": {"data": {"type": "users", "id": "1"}},
+						"comments": {"data": [{"type": "comments", "lid": "new"}], "links": {"self": "/posts/1/relationships/comments"}}
+					},
+					"meta": {"draft": true}
+				},
+				"jsonapi": {"version": "1.1"}
+			}`))).To(BeEmpty())
+		})
+
+		It("accepts new resources without id and arrays of resources", func() {
+			Expect(ValidateRequest(parse(`{"data": [{"type": "posts"}, {"type": "posts", "attributes": {}}]}`))).To(BeEmpty())
+		})
+
+		It("ignores members of extensions", func() {
+			Expect(ValidateRequest(parse(`{"data": {"type": "posts", "version:id": "1"}, "version:meta": 1}`))).To(BeEmpty())
+		})
+
+		It("reports all violations with pointers", func() {
+			Expect(ValidateRequest(parse(`{
+				"data": {
+					"id": 1,
+					"title": "Hello",
+					"attributes": "Hello",
+					"relationships": {
+						"author": {"links": {"self": "/posts/1/relationships/author"}},
+						"comments": {"data": [{"type": "comments"}, "2"]}
+					}
+				},
+				"posts": []
+			}`))).To(Equal([]DocumentError{
+				{Pointer: "/posts", Detail: "posts is not a valid top-level member"},
+				{Pointer: "/data/title", Detail: "title is not a valid member of a resource object"},
+				{Pointer: "/data/type", Detail: "type is missing"},
+				{Pointer: "/data/id", Detail: "id must be a string"},
+				{Pointer: "/data/attributes", Detail: "attributes must be an object"},
+				{Pointer: "/data/relationships/author/data", Detail: "relationships of request documents must contain data"},
+				{Pointer: "/data/relationships/comments/data/0/id", Detail: "id is missing"},
+				{Pointer: "/data/relationships/comments/data/1", Detail: "resource identifier must be an object"},
+			}))
+		})
+
+		It("requires data", func() {
+			Expect(ValidateRequest(parse(`{"meta": {}}`))).To(Equal([]DocumentError{
+				{Pointer: "/data", Detail: "request documents must contain data"},
+			}))
+		})
+
+		It("rejects attributes that are named like members or relationships", func() {
+			Expect(ValidateRequest(parse(`{"data": {
+				"type": "posts",
+				"attributes": {"id": "1", "author": "Marvin"},
+				"relationships": {"author": {"data": null}}
+			}}`))).To(Equal([]DocumentError{
+				{Pointer: "/data/attributes/author", Detail: "author must not be an attribute and a relationship"},
+				{Pointer: "/data/attributes/id", Detail: "attribute must not be named id"},
+			}))
+		})
+
+		It("escapes member names in pointers", func() {
+			Expect(ValidateRequest(parse(`{"data": {"type": "posts", "a/b~c": 1}}`))).To(Equal([]DocumentError{
+				{Pointer: "/data/a~1b~0c", Detail: "a/b~c is not a valid member of a resource object"},
+			}))
+		})
+	})
+
+	Context("update documents", func() {
+		It("accepts resource objects with id", func() {
+			Expect(ValidateUpdateRequest(parse(`{"data": {"type": "posts", "id": "1", "attributes": {"title": "Hello"}}}`))).To(BeEmpty())
+		})
+
+		It("requires an id", func() {
+			Expect(ValidateUpdateRequest(parse(`{"data": {"type": "posts", "lid": "new"}}`))).To(Equal([]DocumentError{
+				{Pointer: "/data/id", Detail: "id is missing"},
+			}))
+		})
+
+		It("rejects arrays", func() {
+			Expect(ValidateUpdateRequest(parse(`{"data": [{"type": "posts", "id": "1"}]}`))).To(Equal([]DocumentError{
+				{Pointer: "/data", Detail: "data of update requests must be a resource object"},
+			}))
+		})
+	})
+
+	Context("relationship documents", func() {
+		It("accepts resource linkage", func() {
+			Expect(ValidateRelationshipRequest(parse(`{"data": null}`))).To(BeEmpty())
+			Expect(ValidateRelationshipRequest(parse(`{"data": {"type": "users", "id": "1"}}`))).To(BeEmpty())
+			Expect(ValidateRelationshipRequest(parse(`{"data": []}`))).To(BeEmpty())
+		})
+
+		It("rejects resource objects", func() {
+			Expect(ValidateRelationshipRequest(parse(`{"data": {"type": "users", "id": "1", "attributes": {}}}`))).To(Equal([]DocumentError{
+				{Pointer: "/data/attributes", Detail: "attributes is not a valid member of a resource identifier"},
+			}))
+		})
+
+		It("rejects other values", func() {
+			Expect(ValidateRelationshipRequest(parse(`{"data": "1"}`))).To(Equal([]DocumentError{
+				{Pointer: "/data", Detail: "resource linkage must be null, an object or an array"},
+			}))
+		})
+	})
+})